@@ -0,0 +1,19 @@
+package starriver
+
+import "context"
+
+type attemptsKey struct{}
+
+// WithAttempts returns a copy of ctx carrying the current attempt of the executing task.
+func WithAttempts(ctx context.Context, attempts int) context.Context {
+	return context.WithValue(ctx, attemptsKey{}, attempts)
+}
+
+// GetAttempts returns the current attempt (start from 1) of the executing task, 0 means unknown.
+// The DataContext passed to Execute, AfterExecute and Listener can be used directly.
+func GetAttempts(ctx context.Context) int {
+	if attempts, ok := ctx.Value(attemptsKey{}).(int); ok {
+		return attempts
+	}
+	return 0
+}
@@ -0,0 +1,46 @@
+package starriver
+
+import (
+	"context"
+	"time"
+)
+
+const (
+	CacheScopePipeline CacheScope = "pipeline" // shared by the runs of the same pipeline, the default scope
+	CacheScopeGlobal   CacheScope = "global"   // shared by all the pipelines using the cache
+	CacheScopeRequest  CacheScope = "request"  // shared by the tasks of the same run only, e.g. the items of a loop
+)
+
+type (
+	CacheScope string
+
+	// CachePolicy reuses the outputs of the task when the component is executed with the same parameters
+	CachePolicy struct {
+		Key   string         `json:"key" yaml:"key"`     // text/template rendered with the parameter, default is the whole parameter
+		TTL   *time.Duration `json:"ttl" yaml:"ttl"`     // never expires if empty
+		Scope CacheScope     `json:"scope" yaml:"scope"` // pipeline, global or request, default is pipeline
+	}
+
+	// ResultCache stores the outputs of the cached tasks, it must be safe for concurrent use
+	ResultCache interface {
+		// Get returns the outputs of the key, false if missing or expired
+		Get(key string) (map[string]interface{}, bool)
+		// Set stores the outputs of the key, the zero ttl means never expires
+		Set(key string, data map[string]interface{}, ttl time.Duration)
+	}
+
+	resultCacheKey struct{}
+)
+
+// ContextWithResultCache returns a copy of ctx carrying the cache, the cached tasks run with the ctx use it
+func ContextWithResultCache(ctx context.Context, cache ResultCache) context.Context {
+	return context.WithValue(ctx, resultCacheKey{}, cache)
+}
+
+// ResultCacheFromContext returns the cache of ctx, it's nil if ctx has no cache, then the tasks are not cached
+func ResultCacheFromContext(ctx context.Context) ResultCache {
+	if cache, ok := ctx.Value(resultCacheKey{}).(ResultCache); ok && cache != nil {
+		return cache
+	}
+	return nil
+}
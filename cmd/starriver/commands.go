@@ -0,0 +1,315 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/thanksloving/starriver"
+	"github.com/thanksloving/starriver/flow"
+)
+
+type (
+	// dataFlag collects the repeated --data key=value, the value is parsed as json if it can, otherwise it's a string
+	dataFlag map[string]interface{}
+
+	// runOutput is the printed Result, the snapshot is saved to a file instead
+	runOutput struct {
+		RequestID string                          `json:"request_id"`
+		Status    starriver.PipelineStatus        `json:"status"`
+		State     map[string]starriver.TaskStatus `json:"state"`
+		Skipped   []string                        `json:"skipped,omitempty"`
+		Data      map[string]interface{}          `json:"data,omitempty"`
+		Attempts  map[string]int                  `json:"attempts,omitempty"`
+		Error     string                          `json:"error,omitempty"`
+		Report    *starriver.Report               `json:"report,omitempty"`
+
+		Compensations []starriver.CompensationRecord `json:"compensations,omitempty"`
+		Hooks         []starriver.TaskRecord         `json:"hooks,omitempty"`
+	}
+)
+
+// errSilent means the problems are printed already
+var errSilent = errors.New("silent")
+
+func (df dataFlag) String() string {
+	return fmt.Sprintf("%v", map[string]interface{}(df))
+}
+
+func (df dataFlag) Set(s string) error {
+	key, value, ok := strings.Cut(s, "=")
+	if !ok || key == "" {
+		return fmt.Errorf("%q is not key=value", s)
+	}
+	var v interface{}
+	if err := json.Unmarshal([]byte(value), &v); err != nil {
+		v = value
+	}
+	df[key] = v
+	return nil
+}
+
+func newFlagSet(name string, stderr io.Writer) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	return fs
+}
+
+// loadPipeline reads the pipeline file, it's json if the extension is .json, otherwise yaml
+func loadPipeline(fs *flag.FlagSet) (*starriver.PipelineConf, error) {
+	if fs.NArg() != 1 {
+		return nil, fmt.Errorf("one pipeline file is required")
+	}
+	path := fs.Arg(0)
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		return flow.LoadPipelineByJson(string(content))
+	}
+	return flow.LoadPipelineByYaml(string(content))
+}
+
+func validateCommand(args []string, stdout, stderr io.Writer) error {
+	fs := newFlagSet("validate", stderr)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	conf, err := loadPipeline(fs)
+	if err != nil {
+		return err
+	}
+	if errs := flow.Validate(*conf); len(errs) > 0 {
+		for _, e := range errs {
+			fmt.Fprintln(stderr, e.Error())
+		}
+		return errSilent
+	}
+	fmt.Fprintf(stdout, "pipeline %q is valid\n", conf.Name)
+	return nil
+}
+
+func runCommand(args []string, stdout, stderr io.Writer) error {
+	fs := newFlagSet("run", stderr)
+	data := make(dataFlag)
+	fs.Var(data, "data", "initial data key=value, the value is parsed as json if it can, can be repeated")
+	input := fs.String("input", "", "json file of the initial data, --data overrides it")
+	requestID := fs.String("request-id", "", "request id of the run")
+	snapshot := fs.String("snapshot", "", "file to save the snapshot if the run is blocked")
+	state := fs.String("state", "", "file to save the task state if the run is blocked")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	conf, err := loadPipeline(fs)
+	if err != nil {
+		return err
+	}
+	initialData := make(map[string]interface{})
+	if *input != "" {
+		content, err := os.ReadFile(*input)
+		if err != nil {
+			return err
+		}
+		if err = json.Unmarshal(content, &initialData); err != nil {
+			return fmt.Errorf("invalid input file %q: %v", *input, err)
+		}
+	}
+	for key, val := range data {
+		initialData[key] = val
+	}
+	pipeline, err := flow.NewPipeline(*conf)
+	if err != nil {
+		return err
+	}
+	var dataContext starriver.DataContext
+	if *requestID != "" {
+		dataContext = flow.NewDataContext(context.Background(), pipeline, initialData, flow.SetRequestID(*requestID))
+	} else {
+		dataContext = flow.NewDataContext(context.Background(), pipeline, initialData)
+	}
+	return runPipeline(dataContext, pipeline, *snapshot, *state, stdout)
+}
+
+func resumeCommand(args []string, stdout, stderr io.Writer) error {
+	fs := newFlagSet("resume", stderr)
+	data := make(dataFlag)
+	fs.Var(data, "data", "data put into the shared data before resuming key=value, can be repeated")
+	requestID := fs.String("request-id", "", "request id of the run")
+	snapshot := fs.String("snapshot", "", "snapshot file of the blocked run, it's updated if the run is blocked again")
+	state := fs.String("state", "", "task state file of the blocked run, it's updated if the run is blocked again")
+	repositoryDir := fs.String("repository", "", "pipeline repository directory, the pipeline of the version in the pipeline file is resumed from it")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *snapshot == "" || *state == "" {
+		return fmt.Errorf("--snapshot and --state are required")
+	}
+	var repository starriver.PipelineRepository
+	if *repositoryDir != "" {
+		var err error
+		if repository, err = flow.NewDirPipelineRepository(*repositoryDir); err != nil {
+			return err
+		}
+	}
+	conf, err := loadPipeline(fs)
+	if err != nil {
+		return err
+	}
+	content, err := os.ReadFile(*snapshot)
+	if err != nil {
+		return err
+	}
+	store := flow.NewSharedDataStore()
+	if err = store.Unmarshal(content); err != nil {
+		return fmt.Errorf("invalid snapshot file %q: %v", *snapshot, err)
+	}
+	content, err = os.ReadFile(*state)
+	if err != nil {
+		return err
+	}
+	taskStatuses := make(map[string]starriver.TaskStatus)
+	if err = json.Unmarshal(content, &taskStatuses); err != nil {
+		return fmt.Errorf("invalid state file %q: %v", *state, err)
+	}
+	var (
+		dataContext starriver.DataContext
+		pipeline    starriver.Pipeline
+	)
+	if *requestID != "" {
+		dataContext, pipeline, err = flow.Rebuild(context.Background(), repository, *conf, taskStatuses, store, data, flow.SetRequestID(*requestID))
+	} else {
+		dataContext, pipeline, err = flow.Rebuild(context.Background(), repository, *conf, taskStatuses, store, data)
+	}
+	if err != nil {
+		return err
+	}
+	return runPipeline(dataContext, pipeline, *snapshot, *state, stdout)
+}
+
+// runPipeline prints the result, and saves the snapshot and state if the run is blocked
+func runPipeline(dataContext starriver.DataContext, pipeline starriver.Pipeline, snapshot, state string, stdout io.Writer) error {
+	engine := flow.NewRiverEngine()
+	defer engine.Destroy()
+	requestID := dataContext.GetRequestID()
+	result := engine.Run(dataContext, pipeline)
+	output := runOutput{
+		RequestID: requestID,
+		Status:    result.Status,
+		State:     result.State,
+		Skipped:   result.Skipped,
+		Data:      result.Data,
+		Attempts:  result.Attempts,
+		Report:    result.Report,
+
+		Compensations: result.Compensations,
+		Hooks:         result.Hooks,
+	}
+	if result.Error != nil {
+		output.Error = result.Error.Error()
+	}
+	if err := writeJSON(stdout, output); err != nil {
+		return err
+	}
+	if result.Status == starriver.PipelineStatusBlocked && snapshot != "" && state != "" {
+		if err := os.WriteFile(snapshot, result.Snapshot, 0o644); err != nil {
+			return err
+		}
+		content, err := json.MarshalIndent(result.State, "", "  ")
+		if err != nil {
+			return err
+		}
+		if err = os.WriteFile(state, content, 0o644); err != nil {
+			return err
+		}
+	}
+	if result.Status == starriver.PipelineStatusFailure {
+		return errSilent
+	}
+	return nil
+}
+
+func graphCommand(args []string, stdout, stderr io.Writer) error {
+	fs := newFlagSet("graph", stderr)
+	format := fs.String("format", "dot", "dot or mermaid")
+	state := fs.String("state", "", "task state file to colour the tasks")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	conf, err := loadPipeline(fs)
+	if err != nil {
+		return err
+	}
+	var options []flow.ExportOption
+	if *state != "" {
+		content, err := os.ReadFile(*state)
+		if err != nil {
+			return err
+		}
+		taskStatuses := make(map[string]starriver.TaskStatus)
+		if err = json.Unmarshal(content, &taskStatuses); err != nil {
+			return fmt.Errorf("invalid state file %q: %v", *state, err)
+		}
+		options = append(options, flow.WithState(taskStatuses))
+	}
+	switch *format {
+	case "dot":
+		_, err = io.WriteString(stdout, flow.ExportDOT(*conf, options...))
+	case "mermaid":
+		_, err = io.WriteString(stdout, flow.ExportMermaid(*conf, options...))
+	default:
+		err = fmt.Errorf("unknown format %q", *format)
+	}
+	return err
+}
+
+func componentsCommand(args []string, stdout, stderr io.Writer) error {
+	fs := newFlagSet("components", stderr)
+	asJSON := fs.Bool("json", false, "print as json")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	components := flow.DescribeComponents()
+	if *asJSON {
+		return writeJSON(stdout, components)
+	}
+	tw := tabwriter.NewWriter(stdout, 0, 4, 2, ' ', 0)
+	for _, component := range components {
+		name := component.Name
+		if component.Namespace != "" {
+			name = component.Namespace + "/" + name
+		}
+		fmt.Fprintf(tw, "%s\t%s\n", name, component.Desc)
+		for _, input := range component.Input {
+			required := ""
+			if input.Required {
+				required = "required"
+			}
+			fmt.Fprintf(tw, "  input\t%s\t%s\t%s\t%s\n", input.Key, input.Type, required, input.Desc)
+		}
+		keys := make([]string, 0, len(component.Output))
+		for key := range component.Output {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			output := component.Output[key]
+			fmt.Fprintf(tw, "  output\t%s\t%s\t\t%s\n", key, output.Type, output.Desc)
+		}
+	}
+	return tw.Flush()
+}
+
+func writeJSON(w io.Writer, v interface{}) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
@@ -0,0 +1,62 @@
+// Command starriver validates, runs, resumes and draws the pipelines defined in yaml or json files.
+//
+//	starriver validate pipeline.yml
+//	starriver run [--data key=value]... [--input data.json] [--snapshot file --state file] pipeline.yml
+//	starriver graph [--format dot|mermaid] [--state file] pipeline.yml
+//	starriver components [--json]
+//	starriver resume --snapshot file --state file [--repository dir] [--data key=value]... pipeline.yml
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+)
+
+type command struct {
+	name  string
+	usage string
+	run   func(args []string, stdout, stderr io.Writer) error
+}
+
+var commands = []command{
+	{"validate", "validate <pipeline file>", validateCommand},
+	{"run", "run [--request-id id] [--data key=value]... [--input data.json] [--snapshot file --state file] <pipeline file>", runCommand},
+	{"graph", "graph [--format dot|mermaid] [--state file] <pipeline file>", graphCommand},
+	{"components", "components [--json]", componentsCommand},
+	{"resume", "resume --snapshot file --state file [--request-id id] [--repository dir] [--data key=value]... <pipeline file>", resumeCommand},
+}
+
+func main() {
+	os.Exit(execute(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+// execute runs the sub command and returns the exit code
+func execute(args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		usage(stderr)
+		return 2
+	}
+	for _, cmd := range commands {
+		if cmd.name != args[0] {
+			continue
+		}
+		if err := cmd.run(args[1:], stdout, stderr); err != nil {
+			if err != errSilent {
+				fmt.Fprintf(stderr, "starriver %s: %v\n", cmd.name, err)
+			}
+			return 1
+		}
+		return 0
+	}
+	fmt.Fprintf(stderr, "unknown command %q\n", args[0])
+	usage(stderr)
+	return 2
+}
+
+func usage(w io.Writer) {
+	fmt.Fprintln(w, "Usage:")
+	for _, cmd := range commands {
+		fmt.Fprintf(w, "  starriver %s\n", cmd.usage)
+	}
+}
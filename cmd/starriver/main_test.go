@@ -0,0 +1,166 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/thanksloving/starriver"
+	"github.com/thanksloving/starriver/flow"
+)
+
+const helloPipeline = `
+name: hello
+result:
+  - out
+pipeline:
+  - task: task1
+    name: Template
+    config:
+      params:
+        - name: Template
+          type: literal
+          literal: hello {{ str "name" }} {{ str "age" }}
+        - name: OutputKey
+          type: literal
+          literal: out
+        - name: Shared
+          type: literal
+          literal: true
+`
+
+const approvalPipeline = `{
+  "name": "approval",
+  "result": ["out"],
+  "pipeline": [
+    {"task": "approve", "name": "WaitForSignal", "config": {"params": [{"name": "Signal", "type": "literal", "literal": "approval"}]}},
+    {"task": "notify", "name": "Template", "depends": [{"task": "approve"}], "config": {"params": [
+      {"name": "Template", "type": "literal", "literal": "{{ str \"signal.approval\" }}"},
+      {"name": "OutputKey", "type": "literal", "literal": "out"},
+      {"name": "Shared", "type": "literal", "literal": true}
+    ]}}
+  ]
+}`
+
+func writeFile(t *testing.T, dir, name, content string) string {
+	path := filepath.Join(dir, name)
+	assert.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	return path
+}
+
+func executeCommand(args ...string) (int, string, string) {
+	var stdout, stderr bytes.Buffer
+	code := execute(args, &stdout, &stderr)
+	return code, stdout.String(), stderr.String()
+}
+
+func TestDataFlag(t *testing.T) {
+	df := make(dataFlag)
+	assert.NoError(t, df.Set("name=jimmy"))
+	assert.NoError(t, df.Set("age=18"))
+	assert.NoError(t, df.Set(`tags=["a","b"]`))
+	assert.NoError(t, df.Set("empty="))
+	assert.Error(t, df.Set("invalid"))
+	assert.Equal(t, dataFlag{"name": "jimmy", "age": float64(18), "tags": []interface{}{"a", "b"}, "empty": ""}, df)
+}
+
+func TestValidate(t *testing.T) {
+	dir := t.TempDir()
+	code, stdout, _ := executeCommand("validate", writeFile(t, dir, "hello.yml", helloPipeline))
+	assert.Equal(t, 0, code)
+	assert.Contains(t, stdout, `pipeline "hello" is valid`)
+
+	code, _, stderr := executeCommand("validate", writeFile(t, dir, "invalid.yml", "name: invalid\npipeline:\n  - task: x\n    name: NotExist\n"))
+	assert.Equal(t, 1, code)
+	assert.Contains(t, stderr, "NotExist")
+
+	code, _, _ = executeCommand("unknown")
+	assert.Equal(t, 2, code)
+}
+
+func TestRun(t *testing.T) {
+	dir := t.TempDir()
+	pipeline := writeFile(t, dir, "hello.yml", helloPipeline)
+	input := writeFile(t, dir, "input.json", `{"name": "tom", "age": 18}`)
+
+	code, stdout, stderr := executeCommand("run", "--input", input, "--data", "name=jimmy", "--request-id", "1", pipeline)
+	assert.Equal(t, 0, code, stderr)
+	var output runOutput
+	assert.NoError(t, json.Unmarshal([]byte(stdout), &output))
+	assert.Equal(t, "hello#1", output.RequestID)
+	assert.Equal(t, starriver.PipelineStatusSuccess, output.Status)
+	assert.Equal(t, "hello jimmy 18", output.Data["out"])
+	assert.NotNil(t, output.Report)
+}
+
+func TestResume(t *testing.T) {
+	dir := t.TempDir()
+	pipeline := writeFile(t, dir, "approval.json", approvalPipeline)
+	snapshot, state := filepath.Join(dir, "snapshot"), filepath.Join(dir, "state.json")
+
+	code, stdout, _ := executeCommand("run", "--snapshot", snapshot, "--state", state, pipeline)
+	assert.Equal(t, 0, code)
+	var output runOutput
+	assert.NoError(t, json.Unmarshal([]byte(stdout), &output))
+	assert.Equal(t, starriver.PipelineStatusBlocked, output.Status)
+	assert.FileExists(t, snapshot)
+	assert.FileExists(t, state)
+
+	code, stdout, stderr := executeCommand("resume", "--snapshot", snapshot, "--state", state, "--data", starriver.SignalKey("approval")+"=approved", pipeline)
+	assert.Equal(t, 0, code, stderr)
+	output = runOutput{}
+	assert.NoError(t, json.Unmarshal([]byte(stdout), &output))
+	assert.Equal(t, starriver.PipelineStatusSuccess, output.Status)
+	assert.Equal(t, "approved", output.Data["out"])
+
+	// the version of the pipeline file is resumed from the repository
+	repositoryDir := filepath.Join(dir, "repository")
+	repository, err := flow.NewDirPipelineRepository(repositoryDir)
+	assert.NoError(t, err)
+	conf, err := flow.LoadPipelineByJson(strings.Replace(approvalPipeline, `{{ str \"signal.approval\" }}`, `v1 {{ str \"signal.approval\" }}`, 1))
+	assert.NoError(t, err)
+	assert.NoError(t, repository.Put("approval", "v1", *conf))
+	versioned := writeFile(t, dir, "versioned.json", strings.Replace(approvalPipeline, `"name": "approval",`, `"name": "approval", "version": "v1",`, 1))
+	code, stdout, stderr = executeCommand("resume", "--snapshot", snapshot, "--state", state, "--repository", repositoryDir,
+		"--data", starriver.SignalKey("approval")+"=approved", versioned)
+	assert.Equal(t, 0, code, stderr)
+	output = runOutput{}
+	assert.NoError(t, json.Unmarshal([]byte(stdout), &output))
+	assert.Equal(t, "v1 approved", output.Data["out"])
+
+	code, _, stderr = executeCommand("resume", pipeline)
+	assert.Equal(t, 1, code)
+	assert.Contains(t, stderr, "--snapshot and --state are required")
+}
+
+func TestGraphAndComponents(t *testing.T) {
+	dir := t.TempDir()
+	pipeline := writeFile(t, dir, "approval.json", approvalPipeline)
+	state := writeFile(t, dir, "state.json", `{"approve": "blocked"}`)
+
+	code, stdout, _ := executeCommand("graph", "--state", state, pipeline)
+	assert.Equal(t, 0, code)
+	assert.Contains(t, stdout, "digraph")
+	assert.Contains(t, stdout, "blocked")
+
+	code, stdout, _ = executeCommand("graph", "--format", "mermaid", pipeline)
+	assert.Equal(t, 0, code)
+	assert.Contains(t, stdout, "flowchart")
+
+	code, _, _ = executeCommand("graph", "--format", "svg", pipeline)
+	assert.Equal(t, 1, code)
+
+	code, stdout, _ = executeCommand("components")
+	assert.Equal(t, 0, code)
+	assert.Contains(t, stdout, "WaitForSignal")
+	assert.Contains(t, stdout, "Signal")
+
+	code, stdout, _ = executeCommand("components", "--json")
+	assert.Equal(t, 0, code)
+	assert.Contains(t, stdout, `"name": "WaitForSignal"`)
+}
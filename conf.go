@@ -5,28 +5,52 @@ import "time"
 type (
 	PipelineConf struct {
 		Name        string                 `yaml:"name" json:"name"`
+		Version     string                 `yaml:"version" json:"version"` // set by the PipelineRepository
 		Concurrency *int                   `yaml:"concurrency" json:"concurrency"`
 		Result      []string               `yaml:"result" json:"result"`
 		Timeout     *time.Duration         `yaml:"timeout" json:"timeout"`
 		Env         map[string]interface{} `yaml:"env" json:"env"`
 		Pipeline    []Task                 `yaml:"pipeline" json:"pipeline"`
+		OnSuccess   []Task                 `yaml:"on_success" json:"on_success"` // run one by one after the pipeline succeeded
+		OnFailure   []Task                 `yaml:"on_failure" json:"on_failure"` // run one by one after the pipeline failed, after the compensations
+		Finally     []Task                 `yaml:"finally" json:"finally"`       // run one by one after on_success or on_failure, whatever the pipeline ends
 	}
 
 	Task struct {
-		ID        string        `yaml:"task" json:"task"`
+		ID          string        `yaml:"task" json:"task"`
+		Name        string        `yaml:"name" json:"name"`
+		Namespace   *string       `yaml:"namespace" json:"namespace"`
+		Config      TaskConfigure `yaml:"config" json:"Config"`
+		Depends     []Depend      `yaml:"depends" json:"depends"`
+		TriggerRule TriggerRule   `yaml:"trigger_rule" json:"trigger_rule"` // default is all_success
+		Switch      *Switch       `yaml:"switch" json:"switch"`             // required by the @switch node
+		Compensate  *Compensation `yaml:"compensate" json:"compensate"`     // undoes the task when the pipeline fails
+	}
+
+	// Compensation is the component which undoes the succeeded task, it runs when the pipeline fails
+	Compensation struct {
 		Name      string        `yaml:"name" json:"name"`
 		Namespace *string       `yaml:"namespace" json:"namespace"`
-		Config    TaskConfigure `yaml:"config" json:"Config"`
-		Depends   []Depend      `yaml:"depends" json:"depends"`
+		Config    TaskConfigure `yaml:"config" json:"config"`
+	}
+
+	// Switch is how the @switch node selects the branch, the value of the key or the expression is the branch name
+	Switch struct {
+		Key  string `yaml:"key" json:"key"`
+		Expr string `yaml:"expr" json:"expr"` // instead of key when it's not empty
 	}
 
 	Depend struct {
-		ID        string `yaml:"task" json:"task"`
-		Condition *struct {
-			Key      string            `yaml:"key" json:"key"`
-			Value    interface{}       `yaml:"value" json:"value"`
-			Operator ConditionOperator `yaml:"operator" json:"operator"`
-		} `yaml:"condition" json:"condition"`
+		ID         string                 `yaml:"task" json:"task"`
+		Condition  *Condition             `yaml:"condition" json:"condition"`
 		Properties map[string]interface{} `yaml:"properties"`
+		Branch     string                 `yaml:"branch" json:"branch"` // the branch of the depended @switch node
+	}
+
+	Condition struct {
+		Key      string            `yaml:"key" json:"key"`
+		Value    interface{}       `yaml:"value" json:"value"`
+		Operator ConditionOperator `yaml:"operator" json:"operator"`
+		Expr     string            `yaml:"expr" json:"expr"` // boolean expression, instead of key/value/operator when it's not empty
 	}
 )
@@ -0,0 +1,138 @@
+package flow
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/thanksloving/starriver"
+	"github.com/thanksloving/starriver/internal/core"
+)
+
+type (
+	// RunHandle is the handle of an asynchronous run started by RunAsync
+	RunHandle struct {
+		requestID string
+		cancel    context.CancelFunc
+		changes   chan TaskStatusChange
+		done      chan struct{}
+		result    starriver.Result
+	}
+
+	// TaskStatusChange is sent when a task of the run is executed
+	TaskStatusChange struct {
+		TaskID string               `json:"task_id"`
+		Status starriver.TaskStatus `json:"status"`
+		Time   time.Time            `json:"time"`
+	}
+
+	// liveRun is a run of the engine which is not done
+	liveRun struct {
+		cancel context.CancelFunc
+	}
+)
+
+// RunAsync runs the pipeline in the background, the returned handle waits for, cancels or watches the run.
+func (re *RiverEngine) RunAsync(dataContext starriver.DataContext, pipeline starriver.Pipeline) *RunHandle {
+	conf, _ := core.PipelineConf(pipeline)
+	h := &RunHandle{
+		requestID: dataContext.GetRequestID(),
+		cancel:    core.WithCancel(dataContext),
+		// every task of the pipeline is executed once at most, so sending never blocks the run
+		changes: make(chan TaskStatusChange, len(conf.Pipeline)),
+		done:    make(chan struct{}),
+	}
+	core.AddTaskHook(dataContext, func(dc starriver.DataContext, taskID string, resp starriver.Response) {
+		if dc.Pipeline() != pipeline {
+			// the tasks of the sub pipelines
+			return
+		}
+		h.changes <- TaskStatusChange{TaskID: taskID, Status: resp.GetStatus(), Time: time.Now()}
+	})
+	go func() {
+		defer close(h.done)
+		h.result = re.Run(dataContext, pipeline)
+		close(h.changes)
+	}()
+	return h
+}
+
+func (h *RunHandle) RequestID() string {
+	return h.requestID
+}
+
+// Wait waits for the run done, or returns the error of the ctx when the ctx is done first
+func (h *RunHandle) Wait(ctx context.Context) (starriver.Result, error) {
+	select {
+	case <-h.done:
+		return h.result, nil
+	case <-ctx.Done():
+		return starriver.Result{}, ctx.Err()
+	}
+}
+
+// Cancel stops the run, the tasks not executed yet are never executed, it's safe to call after the run is done.
+func (h *RunHandle) Cancel() {
+	h.cancel()
+}
+
+// Status returns PipelineStatusRunning if the run is not done, otherwise the status of the result
+func (h *RunHandle) Status() starriver.PipelineStatus {
+	select {
+	case <-h.done:
+		return h.result.Status
+	default:
+		return starriver.PipelineStatusRunning
+	}
+}
+
+// Done is closed when the run is done
+func (h *RunHandle) Done() <-chan struct{} {
+	return h.done
+}
+
+// Changes receives the status of each executed task of the pipeline, it's closed when the run is done.
+func (h *RunHandle) Changes() <-chan TaskStatusChange {
+	return h.changes
+}
+
+// Cancel stops the live run of the engine, started by Run, RunAsync, Resume or the schedules
+func (re *RiverEngine) Cancel(requestID string) error {
+	re.liveLock.Lock()
+	run, ok := re.live[requestID]
+	re.liveLock.Unlock()
+	if !ok {
+		return fmt.Errorf("run %q not found", requestID)
+	}
+	run.cancel()
+	return nil
+}
+
+// LiveRuns returns the request ids of the runs which are not done
+func (re *RiverEngine) LiveRuns() []string {
+	re.liveLock.Lock()
+	defer re.liveLock.Unlock()
+	requestIDs := make([]string, 0, len(re.live))
+	for requestID := range re.live {
+		requestIDs = append(requestIDs, requestID)
+	}
+	sort.Strings(requestIDs)
+	return requestIDs
+}
+
+// register records the live run, it returns the function to remove the record when the run is done
+func (re *RiverEngine) register(requestID string, cancel context.CancelFunc) func() {
+	run := &liveRun{cancel: cancel}
+	re.liveLock.Lock()
+	defer re.liveLock.Unlock()
+	re.live[requestID] = run
+	return func() {
+		re.liveLock.Lock()
+		defer re.liveLock.Unlock()
+		// the run with the same request id may be started later
+		if re.live[requestID] == run {
+			delete(re.live, requestID)
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package flow
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/thanksloving/starriver"
+)
+
+func TestRunAsync(t *testing.T) {
+	conf := starriver.PipelineConf{
+		Name:   "test_async",
+		Result: []string{"out"},
+		Pipeline: []starriver.Task{
+			{
+				ID:   "wait",
+				Name: "Wait",
+				Config: starriver.TaskConfigure{
+					Params: []starriver.Param{{Name: "WaitingTime", Type: starriver.ParamTypeLiteral, Literal: "50ms"}},
+				},
+			},
+			{
+				ID:   "hello",
+				Name: "Template",
+				Config: starriver.TaskConfigure{
+					Params: []starriver.Param{
+						{Name: "Template", Type: starriver.ParamTypeLiteral, Literal: `hello {{ str "name" }}`},
+						{Name: "OutputKey", Type: starriver.ParamTypeLiteral, Literal: "out"},
+						{Name: "Shared", Type: starriver.ParamTypeLiteral, Literal: true},
+					},
+				},
+				Depends: []starriver.Depend{{ID: "wait"}},
+			},
+		},
+	}
+	re := NewRiverEngine()
+	defer re.Destroy()
+
+	pipeline, err := NewPipeline(conf)
+	assert.NoError(t, err)
+	h := re.RunAsync(NewDataContext(context.Background(), pipeline, map[string]interface{}{"name": "jimmy"}, SetRequestID("1")), pipeline)
+	assert.Equal(t, "test_async#1", h.RequestID())
+	assert.Equal(t, starriver.PipelineStatusRunning, h.Status())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	_, err = h.Wait(ctx)
+	cancel()
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Equal(t, []string{"test_async#1"}, re.LiveRuns())
+
+	changes := make([]string, 0)
+	for change := range h.Changes() {
+		changes = append(changes, change.TaskID+":"+string(change.Status))
+	}
+	assert.Equal(t, []string{"wait:success", "hello:success"}, changes)
+
+	result, err := h.Wait(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, starriver.PipelineStatusSuccess, result.Status)
+	assert.Equal(t, starriver.PipelineStatusSuccess, h.Status())
+	assert.Equal(t, "hello jimmy", result.Data["out"])
+	assert.Empty(t, re.LiveRuns())
+	// it's safe to cancel the done run
+	h.Cancel()
+}
+
+func TestRunAsync_Cancel(t *testing.T) {
+	re := NewRiverEngine()
+	defer re.Destroy()
+
+	pipeline, err := NewPipeline(waitPipeline("10s"))
+	assert.NoError(t, err)
+	h := re.RunAsync(NewDataContext(context.Background(), pipeline, nil, SetRequestID("1")), pipeline)
+	h.Cancel()
+	result, err := h.Wait(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, starriver.PipelineStatusFailure, result.Status)
+
+	// cancel the synchronous run by the request id
+	assert.Error(t, re.Cancel("test_schedule#2"))
+	pipeline, err = NewPipeline(waitPipeline("10s"))
+	assert.NoError(t, err)
+	done := make(chan starriver.Result)
+	go func() {
+		done <- re.Run(NewDataContext(context.Background(), pipeline, nil, SetRequestID("2")), pipeline)
+	}()
+	assert.Eventually(t, func() bool {
+		return re.Cancel("test_schedule#2") == nil
+	}, time.Second, time.Millisecond)
+	select {
+	case result = <-done:
+		assert.Equal(t, starriver.PipelineStatusFailure, result.Status)
+	case <-time.After(time.Second):
+		assert.Fail(t, "the run is not cancelled")
+	}
+	assert.Empty(t, re.LiveRuns())
+}
@@ -0,0 +1,210 @@
+package flow
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/thanksloving/starriver"
+	"github.com/thanksloving/starriver/helper"
+	"github.com/thanksloving/starriver/registry"
+)
+
+type (
+	cachedComponent struct {
+		helper.Skeleton
+		executed *int32
+	}
+
+	cachedParam struct {
+		Text string
+		Lang string
+	}
+
+	// listenedComponent counts the callbacks of the cached component
+	listenedComponent struct {
+		cachedComponent
+		succeeded, after *int32
+	}
+)
+
+func (c *cachedComponent) ParameterNew() interface{} {
+	return &cachedParam{}
+}
+
+func (c *cachedComponent) Execute(_ starriver.DataContext, param interface{}) starriver.Response {
+	atomic.AddInt32(c.executed, 1)
+	return helper.NewSuccessDataResponse(map[string]interface{}{"length": len(param.(*cachedParam).Text)})
+}
+
+func (c *listenedComponent) OnSuccess(_ starriver.DataContext, _ map[string]interface{}) {
+	atomic.AddInt32(c.succeeded, 1)
+}
+
+func (c *listenedComponent) OnFailure(_ starriver.DataContext, _ error) {}
+
+func (c *listenedComponent) After(_ starriver.DataContext, _ starriver.Response) {
+	atomic.AddInt32(c.after, 1)
+}
+
+func TestRun_Cache(t *testing.T) {
+	var executed int32
+	registry.Register("TestCached", "可缓存结果的节点", func(id string) starriver.Executable {
+		return &cachedComponent{Skeleton: helper.NewSkeleton(id), executed: &executed}
+	})
+	newConf := func(cache *starriver.CachePolicy) starriver.PipelineConf {
+		return starriver.PipelineConf{
+			Name:   "test_cache",
+			Result: []string{"length"},
+			Pipeline: []starriver.Task{{
+				ID:   "count",
+				Name: "TestCached",
+				Config: starriver.TaskConfigure{
+					Params: []starriver.Param{
+						{Name: "Text", Type: starriver.ParamTypeVariable, Variable: "text"},
+						{Name: "Lang", Type: starriver.ParamTypeVariable, Variable: "lang"},
+					},
+					Cache: cache,
+				},
+			}},
+		}
+	}
+	re := NewRiverEngine()
+	defer re.Destroy()
+	run := func(conf starriver.PipelineConf, data map[string]interface{}) starriver.Result {
+		pipeline, err := NewPipeline(conf)
+		assert.NoError(t, err)
+		result := re.Run(NewDataContext(context.Background(), pipeline, data), pipeline)
+		assert.Equal(t, starriver.PipelineStatusSuccess, result.Status)
+		return result
+	}
+	cacheHit := func(result starriver.Result) bool {
+		for _, record := range result.Report.Tasks {
+			if record.TaskID == "count" {
+				return record.CacheHit
+			}
+		}
+		return false
+	}
+
+	t.Run("parameter", func(t *testing.T) {
+		atomic.StoreInt32(&executed, 0)
+		conf := newConf(&starriver.CachePolicy{})
+		result := run(conf, map[string]interface{}{"text": "hello", "lang": "en"})
+		assert.False(t, cacheHit(result))
+		result = run(conf, map[string]interface{}{"text": "hello", "lang": "en"})
+		assert.True(t, cacheHit(result))
+		assert.Equal(t, map[string]interface{}{"length": 5}, result.Data)
+		assert.Equal(t, int32(1), atomic.LoadInt32(&executed))
+
+		result = run(conf, map[string]interface{}{"text": "hello world", "lang": "en"})
+		assert.False(t, cacheHit(result))
+		assert.Equal(t, map[string]interface{}{"length": 11}, result.Data)
+		assert.Equal(t, int32(2), atomic.LoadInt32(&executed))
+	})
+
+	t.Run("key template", func(t *testing.T) {
+		atomic.StoreInt32(&executed, 0)
+		conf := newConf(&starriver.CachePolicy{Key: "{{ .Lang }}", Scope: starriver.CacheScopeGlobal})
+		run(conf, map[string]interface{}{"text": "hello", "lang": "fr"})
+		result := run(conf, map[string]interface{}{"text": "hello world", "lang": "fr"})
+		assert.True(t, cacheHit(result))
+		assert.Equal(t, map[string]interface{}{"length": 5}, result.Data)
+		assert.Equal(t, int32(1), atomic.LoadInt32(&executed))
+	})
+
+	t.Run("request scope", func(t *testing.T) {
+		atomic.StoreInt32(&executed, 0)
+		conf := newConf(&starriver.CachePolicy{Scope: starriver.CacheScopeRequest})
+		run(conf, map[string]interface{}{"text": "hello", "lang": "de"})
+		result := run(conf, map[string]interface{}{"text": "hello", "lang": "de"})
+		assert.False(t, cacheHit(result))
+		assert.Equal(t, int32(2), atomic.LoadInt32(&executed))
+	})
+
+	t.Run("no cache", func(t *testing.T) {
+		atomic.StoreInt32(&executed, 0)
+		conf := newConf(nil)
+		run(conf, map[string]interface{}{"text": "hello", "lang": "en"})
+		run(conf, map[string]interface{}{"text": "hello", "lang": "en"})
+		assert.Equal(t, int32(2), atomic.LoadInt32(&executed))
+	})
+}
+
+func TestValidate_Cache(t *testing.T) {
+	conf := starriver.PipelineConf{
+		Name: "test_validate_cache",
+		Pipeline: []starriver.Task{
+			{ID: "task1", Name: "TestNode", Config: starriver.TaskConfigure{Cache: &starriver.CachePolicy{Scope: "forever"}}},
+			{ID: "task2", Name: "TestNode", Config: starriver.TaskConfigure{Cache: &starriver.CachePolicy{Key: "{{ .Pass"}},
+				Depends: []starriver.Depend{{ID: "task1"}}},
+			{ID: "any", Name: "@any", Config: starriver.TaskConfigure{Cache: &starriver.CachePolicy{}},
+				Depends: []starriver.Depend{{ID: "task2"}}},
+			{ID: "task3", Name: "TestNoParameter", Config: starriver.TaskConfigure{Cache: &starriver.CachePolicy{}},
+				Depends: []starriver.Depend{{ID: "any"}}},
+		},
+	}
+	registry.Register("TestNoParameter", "没有参数的节点", func(id string) starriver.Executable {
+		return &childComponent{Skeleton: helper.NewSkeleton(id)}
+	})
+	errs := Validate(conf)
+	if assert.Len(t, errs, 4) {
+		assert.Equal(t, "pipeline[0].config.cache.scope", errs[0].Path)
+		assert.Equal(t, "pipeline[1].config.cache.key", errs[1].Path)
+		assert.Equal(t, "pipeline[2].config.cache", errs[2].Path)
+		assert.Equal(t, "pipeline[3].config.cache.key", errs[3].Path)
+	}
+
+	// all the calls of the component without parameters would share the key
+	conf.Pipeline = conf.Pipeline[3:]
+	conf.Pipeline[0].Depends = nil
+	_, err := NewPipeline(conf)
+	assert.ErrorContains(t, err, "cache key is required")
+	conf.Pipeline[0].Config.Cache.Key = "static"
+	assert.Empty(t, Validate(conf))
+	_, err = NewPipeline(conf)
+	assert.NoError(t, err)
+}
+
+func TestRun_CacheListener(t *testing.T) {
+	var executed, succeeded, after int32
+	registry.Register("TestCachedListener", "监听结果的可缓存节点", func(id string) starriver.Executable {
+		return &listenedComponent{
+			cachedComponent: cachedComponent{Skeleton: helper.NewSkeleton(id), executed: &executed},
+			succeeded:       &succeeded,
+			after:           &after,
+		}
+	})
+	conf := starriver.PipelineConf{
+		Name:   "test_cache_listener",
+		Result: []string{"length"},
+		Pipeline: []starriver.Task{{
+			ID:   "count",
+			Name: "TestCachedListener",
+			Config: starriver.TaskConfigure{
+				Params: []starriver.Param{{Name: "Text", Type: starriver.ParamTypeVariable, Variable: "text"}},
+				Cache:  &starriver.CachePolicy{},
+			},
+		}},
+	}
+	re := NewRiverEngine()
+	defer re.Destroy()
+	var records []starriver.TaskRecord
+	for i := 0; i < 2; i++ {
+		pipeline, err := NewPipeline(conf)
+		assert.NoError(t, err)
+		result := re.Run(NewDataContext(context.Background(), pipeline, map[string]interface{}{"text": "hello"}), pipeline)
+		assert.Equal(t, starriver.PipelineStatusSuccess, result.Status)
+		assert.Equal(t, map[string]interface{}{"length": 5}, result.Data)
+		records = append(records, result.Report.Tasks[0])
+	}
+	// the callbacks are called by the execution only, not by the cache hit
+	assert.Equal(t, int32(1), atomic.LoadInt32(&executed))
+	assert.Equal(t, int32(1), atomic.LoadInt32(&succeeded))
+	assert.Equal(t, int32(1), atomic.LoadInt32(&after))
+	assert.False(t, records[0].CacheHit)
+	assert.True(t, records[1].CacheHit)
+	assert.Equal(t, 1, records[1].Attempts)
+}
@@ -0,0 +1,121 @@
+package flow
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/thanksloving/starriver"
+	"github.com/thanksloving/starriver/helper"
+	"github.com/thanksloving/starriver/registry"
+)
+
+type (
+	undoComponent struct {
+		helper.Skeleton
+		undone *undoRecorder
+	}
+
+	undoRecorder struct {
+		lock  sync.Mutex
+		tasks []string
+		data  map[string]interface{}
+	}
+)
+
+func (u *undoComponent) Execute(dataContext starriver.DataContext, _ interface{}) starriver.Response {
+	u.undone.lock.Lock()
+	defer u.undone.lock.Unlock()
+	u.undone.tasks = append(u.undone.tasks, u.ID())
+	if val, ok := dataContext.Get("out"); ok {
+		u.undone.data[u.ID()] = val
+	}
+	if u.ID() == "reserve" {
+		return helper.NewErrorResponse(errors.New("undo reserve failed"))
+	}
+	return helper.NewSuccessResponse()
+}
+
+func TestRun_Compensate(t *testing.T) {
+	undone := &undoRecorder{data: make(map[string]interface{})}
+	registry.Register("TestUndo", "记录补偿的节点", func(id string) starriver.Executable {
+		return &undoComponent{Skeleton: helper.NewSkeleton(id), undone: undone}
+	})
+	template := func(id, out string, depends ...string) starriver.Task {
+		task := starriver.Task{
+			ID:   id,
+			Name: "Template",
+			Config: starriver.TaskConfigure{Params: []starriver.Param{
+				{Name: "Template", Type: starriver.ParamTypeLiteral, Literal: out},
+				{Name: "OutputKey", Type: starriver.ParamTypeLiteral, Literal: "out"},
+				{Name: "Shared", Type: starriver.ParamTypeLiteral, Literal: false},
+			}},
+			Compensate: &starriver.Compensation{Name: "TestUndo"},
+		}
+		for _, depend := range depends {
+			task.Depends = append(task.Depends, starriver.Depend{ID: depend})
+		}
+		return task
+	}
+	conf := starriver.PipelineConf{
+		Name: "test_compensate",
+		Pipeline: []starriver.Task{
+			template("reserve", "stock-1"),
+			template("charge", "charge-1", "reserve"),
+			template("coupon", "coupon-1", "reserve"),
+			{
+				ID:         "notify",
+				Name:       "TestNode",
+				Config:     starriver.TaskConfigure{Params: []starriver.Param{{Name: "Pass", Type: starriver.ParamTypeLiteral, Literal: false}}},
+				Depends:    []starriver.Depend{{ID: "charge"}, {ID: "coupon"}},
+				Compensate: &starriver.Compensation{Name: "TestUndo"},
+			},
+		},
+	}
+	assert.Empty(t, Validate(conf))
+	pipeline, err := NewPipeline(conf)
+	assert.NoError(t, err)
+	result := NewRiverEngine().Run(NewDataContext(context.Background(), pipeline, nil), pipeline)
+	assert.Equal(t, starriver.PipelineStatusFailure, result.Status)
+
+	// notify failed so it's not compensated, reserve is undone after the tasks depending on it
+	if assert.Len(t, result.Compensations, 3) {
+		assert.ElementsMatch(t, []string{"charge", "coupon"}, []string{result.Compensations[0].TaskID, result.Compensations[1].TaskID})
+		assert.Equal(t, "reserve", result.Compensations[2].TaskID)
+		assert.Equal(t, "TestUndo", result.Compensations[2].Component)
+		assert.Equal(t, starriver.TaskStatusFailure, result.Compensations[2].Status)
+		assert.Equal(t, "undo reserve failed", result.Compensations[2].Error)
+		assert.Equal(t, starriver.TaskStatusSuccess, result.Compensations[0].Status)
+	}
+	assert.Equal(t, map[string]interface{}{"reserve": "stock-1", "charge": "charge-1", "coupon": "coupon-1"}, undone.data)
+
+	// nothing is compensated when the pipeline succeeds
+	conf.Pipeline[3].Config.Params[0].Literal = true
+	undone.tasks = nil
+	pipeline, err = NewPipeline(conf)
+	assert.NoError(t, err)
+	result = NewRiverEngine().Run(NewDataContext(context.Background(), pipeline, nil), pipeline)
+	assert.Equal(t, starriver.PipelineStatusSuccess, result.Status)
+	assert.Empty(t, result.Compensations)
+	assert.Empty(t, undone.tasks)
+}
+
+func TestValidate_Compensate(t *testing.T) {
+	conf := starriver.PipelineConf{
+		Name: "test_compensate",
+		Pipeline: []starriver.Task{
+			{ID: "root", Name: "@any", Compensate: &starriver.Compensation{Name: "TestNode"}},
+			{ID: "task", Name: "TestNode", Compensate: &starriver.Compensation{Name: "NotExist"}, Depends: []starriver.Depend{{ID: "root"}}},
+		},
+	}
+	errs := Validate(conf)
+	if assert.Len(t, errs, 2) {
+		assert.Equal(t, "pipeline[0].compensate", errs[0].Path)
+		assert.Equal(t, "pipeline[1].compensate.name", errs[1].Path)
+	}
+	_, err := NewPipeline(conf)
+	assert.Error(t, err)
+}
@@ -0,0 +1,78 @@
+package flow
+
+import (
+	"reflect"
+	"sort"
+)
+
+type (
+	// ComponentInfo is the JSON-serializable metadata of a component
+	ComponentInfo struct {
+		Name      string                `json:"name"`
+		Namespace string                `json:"namespace,omitempty"`
+		Desc      string                `json:"desc"`
+		Timeout   string                `json:"timeout,omitempty"`
+		Input     []InputInfo           `json:"input"`
+		Output    map[string]OutputInfo `json:"output"`
+	}
+
+	InputInfo struct {
+		Key      string        `json:"key"`
+		Desc     string        `json:"desc"`
+		Required bool          `json:"required"`
+		Type     string        `json:"type,omitempty"`
+		Options  []interface{} `json:"options,omitempty"`
+	}
+
+	OutputInfo struct {
+		Desc string `json:"desc"`
+		Type string `json:"type,omitempty"`
+	}
+)
+
+// DescribeComponents returns the metadata of all the registered components, sorted by the namespace and name.
+func DescribeComponents() []ComponentInfo {
+	components := GetComponents()
+	infos := make([]ComponentInfo, 0, len(components))
+	for _, component := range components {
+		info := ComponentInfo{
+			Name:   component.Name,
+			Desc:   component.Desc,
+			Input:  make([]InputInfo, 0, len(component.Input)),
+			Output: make(map[string]OutputInfo, len(component.Output)),
+		}
+		if component.Namespace != nil {
+			info.Namespace = *component.Namespace
+		}
+		if component.Timeout != nil {
+			info.Timeout = component.Timeout.String()
+		}
+		for _, input := range component.Input {
+			info.Input = append(info.Input, InputInfo{
+				Key:      input.Key,
+				Desc:     input.Desc,
+				Required: input.Required,
+				Type:     kindName(input.Type),
+				Options:  input.Options,
+			})
+		}
+		for key, output := range component.Output {
+			info.Output[key] = OutputInfo{Desc: output.Desc, Type: kindName(output.Type)}
+		}
+		infos = append(infos, info)
+	}
+	sort.Slice(infos, func(i, j int) bool {
+		if infos[i].Namespace != infos[j].Namespace {
+			return infos[i].Namespace < infos[j].Namespace
+		}
+		return infos[i].Name < infos[j].Name
+	})
+	return infos
+}
+
+func kindName(kind reflect.Kind) string {
+	if kind == reflect.Invalid {
+		return ""
+	}
+	return kind.String()
+}
@@ -0,0 +1,198 @@
+package flow
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/thanksloving/starriver"
+	"github.com/thanksloving/starriver/internal/dag"
+)
+
+type (
+	ExportOption func(*exporter)
+
+	exporter struct {
+		conf  starriver.PipelineConf
+		state map[string]starriver.TaskStatus
+	}
+
+	// exportEdge is a depend of a task, the label is the condition and the properties
+	exportEdge struct {
+		source, target string
+		label          string
+	}
+)
+
+var (
+	statusColors = map[starriver.TaskStatus]string{
+		starriver.TaskStatusSuccess: "#b7eb8f",
+		starriver.TaskStatusFailure: "#ffa39e",
+		starriver.TaskStatusBlocked: "#ffe58f",
+		starriver.TaskStatusSkipped: "#d9d9d9",
+	}
+
+	dotEscaper     = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+	mermaidEscaper = strings.NewReplacer(`"`, "#quot;", "\n", "<br/>")
+)
+
+// WithState colours the tasks by their status, e.g. Result.State, so we can see where a run stopped.
+func WithState(state map[string]starriver.TaskStatus) ExportOption {
+	return func(e *exporter) {
+		e.state = state
+	}
+}
+
+// ExportDOT renders the pipeline in the Graphviz DOT language,
+// the builtin @any node is a diamond, the @not node is an inverted triangle, the @switch node is a trapezium.
+func ExportDOT(conf starriver.PipelineConf, options ...ExportOption) string {
+	e := newExporter(conf, options...)
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "digraph %q {\n", conf.Name)
+	sb.WriteString("\trankdir=TB;\n")
+	sb.WriteString("\tnode [shape=box, style=rounded];\n")
+	for _, task := range conf.Pipeline {
+		attrs := []string{fmt.Sprintf("label=\"%s\"", dotEscaper.Replace(e.label(task)))}
+		switch e.nodeType(task) {
+		case dag.NodeTypeAny:
+			attrs = append(attrs, "shape=diamond")
+		case dag.NodeTypeNot:
+			attrs = append(attrs, "shape=invtriangle")
+		case dag.NodeTypeSwitch:
+			attrs = append(attrs, "shape=trapezium")
+		}
+		if color, ok := e.color(task.ID); ok {
+			attrs = append(attrs, "style=\"rounded,filled\"", fmt.Sprintf("fillcolor=\"%s\"", color))
+		}
+		fmt.Fprintf(&sb, "\t\"%s\" [%s];\n", dotEscaper.Replace(task.ID), strings.Join(attrs, ", "))
+	}
+	for _, edge := range e.edges() {
+		fmt.Fprintf(&sb, "\t\"%s\" -> \"%s\"", dotEscaper.Replace(edge.source), dotEscaper.Replace(edge.target))
+		if edge.label != "" {
+			fmt.Fprintf(&sb, " [label=\"%s\"]", dotEscaper.Replace(edge.label))
+		}
+		sb.WriteString(";\n")
+	}
+	sb.WriteString("}\n")
+	return sb.String()
+}
+
+// ExportMermaid renders the pipeline as a Mermaid flowchart,
+// the builtin @any node is a rhombus, the @not node is a hexagon, the @switch node is a trapezoid.
+func ExportMermaid(conf starriver.PipelineConf, options ...ExportOption) string {
+	e := newExporter(conf, options...)
+	// the task id may contain the characters mermaid does not allow, so the nodes are named by index
+	ids := make(map[string]string, len(conf.Pipeline))
+	for idx, task := range conf.Pipeline {
+		ids[task.ID] = fmt.Sprintf("n%d", idx)
+	}
+	var sb strings.Builder
+	sb.WriteString("flowchart TD\n")
+	classes := make(map[starriver.TaskStatus][]string)
+	for _, task := range conf.Pipeline {
+		label := mermaidEscaper.Replace(e.label(task))
+		switch e.nodeType(task) {
+		case dag.NodeTypeAny:
+			fmt.Fprintf(&sb, "\t%s{\"%s\"}\n", ids[task.ID], label)
+		case dag.NodeTypeNot:
+			fmt.Fprintf(&sb, "\t%s{{\"%s\"}}\n", ids[task.ID], label)
+		case dag.NodeTypeSwitch:
+			fmt.Fprintf(&sb, "\t%s[/\"%s\"\\]\n", ids[task.ID], label)
+		default:
+			fmt.Fprintf(&sb, "\t%s(\"%s\")\n", ids[task.ID], label)
+		}
+		if _, ok := e.color(task.ID); ok {
+			classes[e.state[task.ID]] = append(classes[e.state[task.ID]], ids[task.ID])
+		}
+	}
+	for _, edge := range e.edges() {
+		source, ok := ids[edge.source]
+		if !ok {
+			continue
+		}
+		if edge.label != "" {
+			fmt.Fprintf(&sb, "\t%s -->|\"%s\"| %s\n", source, mermaidEscaper.Replace(edge.label), ids[edge.target])
+		} else {
+			fmt.Fprintf(&sb, "\t%s --> %s\n", source, ids[edge.target])
+		}
+	}
+	statuses := make([]string, 0, len(classes))
+	for status := range classes {
+		statuses = append(statuses, string(status))
+	}
+	sort.Strings(statuses)
+	for _, status := range statuses {
+		fmt.Fprintf(&sb, "\tclassDef %s fill:%s\n", status, statusColors[starriver.TaskStatus(status)])
+		fmt.Fprintf(&sb, "\tclass %s %s\n", strings.Join(classes[starriver.TaskStatus(status)], ","), status)
+	}
+	return sb.String()
+}
+
+func newExporter(conf starriver.PipelineConf, options ...ExportOption) *exporter {
+	e := &exporter{conf: conf}
+	for _, option := range options {
+		option(e)
+	}
+	return e
+}
+
+func (e *exporter) nodeType(task starriver.Task) dag.NodeType {
+	if name, ok := strings.CutPrefix(task.Name, starriver.BuiltinNodePrefix); ok {
+		return dag.NodeType(name)
+	}
+	return ""
+}
+
+func (e *exporter) label(task starriver.Task) string {
+	if e.nodeType(task) != "" || task.Name == "" {
+		return task.ID
+	}
+	name := task.Name
+	if task.Namespace != nil && *task.Namespace != "" {
+		name = *task.Namespace + "." + name
+	}
+	label := task.ID + "\n" + name
+	if status, ok := e.state[task.ID]; ok {
+		label += "\n" + string(status)
+	}
+	return label
+}
+
+func (e *exporter) color(taskID string) (string, bool) {
+	color, ok := statusColors[e.state[taskID]]
+	return color, ok
+}
+
+func (e *exporter) edges() []exportEdge {
+	edges := make([]exportEdge, 0)
+	for _, task := range e.conf.Pipeline {
+		for _, depend := range task.Depends {
+			edges = append(edges, exportEdge{source: depend.ID, target: task.ID, label: edgeLabel(depend)})
+		}
+	}
+	return edges
+}
+
+// edgeLabel describes the branch, the condition as "key op value" or the expression, followed by the sorted properties
+func edgeLabel(depend starriver.Depend) string {
+	lines := make([]string, 0, len(depend.Properties)+1)
+	if depend.Branch != "" {
+		lines = append(lines, depend.Branch)
+	}
+	if c := depend.Condition; c != nil {
+		if c.Expr != "" {
+			lines = append(lines, c.Expr)
+		} else {
+			lines = append(lines, fmt.Sprintf("%s %s %v", c.Key, c.Operator, c.Value))
+		}
+	}
+	keys := make([]string, 0, len(depend.Properties))
+	for key := range depend.Properties {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		lines = append(lines, fmt.Sprintf("%s=%v", key, depend.Properties[key]))
+	}
+	return strings.Join(lines, "\n")
+}
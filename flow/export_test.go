@@ -0,0 +1,78 @@
+package flow
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/thanksloving/starriver"
+)
+
+func exportTestConf() starriver.PipelineConf {
+	return starriver.PipelineConf{
+		Name: "test_export",
+		Pipeline: []starriver.Task{
+			{ID: "task1", Name: "TestNode"},
+			{
+				ID:   "task2",
+				Name: "Template",
+				Depends: []starriver.Depend{{
+					ID:         "task1",
+					Condition:  &starriver.Condition{Key: "score", Value: 60, Operator: starriver.ConditionGT},
+					Properties: map[string]interface{}{"b": 2, "a": "x"},
+				}},
+			},
+			{
+				ID:      "task3",
+				Name:    "Template",
+				Depends: []starriver.Depend{{ID: "task1", Condition: &starriver.Condition{Expr: `tag == "C4"`}}},
+			},
+			{ID: "any", Name: "@any", Depends: []starriver.Depend{{ID: "task2"}, {ID: "task3"}}},
+			{ID: "not", Name: "@not", Depends: []starriver.Depend{{ID: "any"}}},
+		},
+	}
+}
+
+func TestExportDOT(t *testing.T) {
+	state := map[string]starriver.TaskStatus{"task1": starriver.TaskStatusSuccess, "task2": starriver.TaskStatusBlocked}
+	assert.Equal(t, `digraph "test_export" {
+	rankdir=TB;
+	node [shape=box, style=rounded];
+	"task1" [label="task1\nTestNode\nsuccess", style="rounded,filled", fillcolor="#b7eb8f"];
+	"task2" [label="task2\nTemplate\nblocked", style="rounded,filled", fillcolor="#ffe58f"];
+	"task3" [label="task3\nTemplate"];
+	"any" [label="any", shape=diamond];
+	"not" [label="not", shape=invtriangle];
+	"task1" -> "task2" [label="score > 60\na=x\nb=2"];
+	"task1" -> "task3" [label="tag == \"C4\""];
+	"task2" -> "any";
+	"task3" -> "any";
+	"any" -> "not";
+}
+`, ExportDOT(exportTestConf(), WithState(state)))
+}
+
+func TestExportMermaid(t *testing.T) {
+	state := map[string]starriver.TaskStatus{
+		"task1": starriver.TaskStatusSuccess,
+		"task2": starriver.TaskStatusSuccess,
+		"task3": starriver.TaskStatusFailure,
+		"any":   starriver.TaskStatusInit,
+	}
+	assert.Equal(t, `flowchart TD
+	n0("task1<br/>TestNode<br/>success")
+	n1("task2<br/>Template<br/>success")
+	n2("task3<br/>Template<br/>failure")
+	n3{"any"}
+	n4{{"not"}}
+	n0 -->|"score > 60<br/>a=x<br/>b=2"| n1
+	n0 -->|"tag == #quot;C4#quot;"| n2
+	n1 --> n3
+	n2 --> n3
+	n3 --> n4
+	classDef failure fill:#ffa39e
+	class n2 failure
+	classDef success fill:#b7eb8f
+	class n0,n1 success
+`, ExportMermaid(exportTestConf(), WithState(state)))
+}
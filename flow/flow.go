@@ -3,9 +3,11 @@ package flow
 import (
 	"context"
 	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
 
 	"github.com/robfig/cron/v3"
-	"github.com/sirupsen/logrus"
 	"gopkg.in/yaml.v3"
 
 	"github.com/thanksloving/starriver"
@@ -23,10 +25,34 @@ type (
 		LoggingEnabled    bool
 		DebugEnabled      bool
 		EventHandler      starriver.EventHandler
+		TaskEventHandler  starriver.TaskEventHandler
+		Journal           starriver.RunJournal
+		Tracer            starriver.Tracer
+		Metrics           starriver.MetricsCollector
+		ResultCache       starriver.ResultCache
+		Repository        starriver.PipelineRepository
 		cronClient        *cron.Cron
+		scheduleLock      sync.RWMutex
+		schedules         map[ScheduleID]*schedule
+		lastScheduleID    ScheduleID
+		liveLock          sync.Mutex
+		live              map[string]*liveRun
+		storeFactory      func() starriver.SharedDataStore
+		resumingLock      sync.Mutex
+		resuming          map[string]struct{}
 	}
 
 	Option func(*RiverEngine)
+
+	// RunOptions selects the tasks of a run, the zero value runs all the tasks
+	RunOptions struct {
+		Targets []string // run the targets and their upstream tasks only, the other tasks are ignored
+		From    []string // run the tasks and their downstream tasks again, the succeeded upstream tasks are reused
+	}
+
+	ValidationError = core.ValidationError
+
+	PrometheusCollector = builtin.PrometheusCollector
 )
 
 var (
@@ -34,9 +60,25 @@ var (
 	SetRequestID   = core.SetRequestID
 	// NewSharedDataStore 新建自己的共享数据存储，主要用于修改默认的序列化方式。默认使用的 json 序列化方式对数字类型会有精度损失或类型错乱。
 	NewSharedDataStore = builtin.NewSharedDataStore
-	SetSharedDataStore = core.SetSharedDataStore
-	SetLogger          = core.SetLogger
-	SetLogLevel        = core.SetLogLevel
+	// NewFileSharedDataStore 新建持久化到本地文件的共享数据存储，每次修改都会追加写入文件，进程崩溃后使用相同的流程名和 RequestID 即可恢复数据。
+	NewFileSharedDataStore = builtin.NewFileSharedDataStore
+	SetSharedDataStore     = core.SetSharedDataStore
+	SetLogger              = core.SetLogger
+	SetLogLevel            = core.SetLogLevel
+	// WithFsync 文件共享数据存储每次写入后都同步到磁盘，机器宕机也不会丢失数据，默认关闭
+	WithFsync = builtin.WithFsync
+	// NewMemoryJournal 新建内存中的运行日志，用于测试或者同一进程内恢复 blocked 的流程
+	NewMemoryJournal = builtin.NewMemoryJournal
+	// NewFileJournal 新建持久化到本地文件的运行日志，进程重启后可以通过 RiverEngine.Recover 恢复未完成的流程
+	NewFileJournal = builtin.NewFileJournal
+	// NewPrometheusCollector 新建内存中的指标收集器，同时也是一个 http.Handler，以 Prometheus 文本格式输出指标
+	NewPrometheusCollector = builtin.NewPrometheusCollector
+	// NewMemoryPipelineRepository 新建内存中的流程仓库，按名称和版本保存流程
+	NewMemoryPipelineRepository = builtin.NewMemoryPipelineRepository
+	// NewDirPipelineRepository 新建保存在本地目录的流程仓库，每个版本是一个 yaml 文件
+	NewDirPipelineRepository = builtin.NewDirPipelineRepository
+	// NewLRUResultCache 新建内存中的 LRU 结果缓存，容量为缓存的节点结果个数，默认 1024
+	NewLRUResultCache = builtin.NewLRUResultCache
 )
 
 func LoadPipelineByYaml(yamlConf string) (*starriver.PipelineConf, error) {
@@ -55,14 +97,30 @@ func LoadPipelineByJson(jsonConf string) (*starriver.PipelineConf, error) {
 	return &pc, nil
 }
 
+// Validate checks the pipeline configure without building it, and reports all the problems at once.
+func Validate(conf starriver.PipelineConf) []ValidationError {
+	return core.Validate(conf)
+}
+
 func NewPipeline(conf starriver.PipelineConf) (starriver.Pipeline, error) {
 	return core.BuildPipeline(conf, starriver.PipelineStatusInit, make(map[string]starriver.TaskStatus))
 }
 
-// Rebuild  a pipeline from a snapshot
-func Rebuild(ctx context.Context, conf starriver.PipelineConf, taskStatuses map[string]starriver.TaskStatus,
-	snapshot starriver.SharedDataStore, initialData map[string]interface{},
+// Rebuild  a pipeline from a snapshot. If the repository is not nil and the conf has the version, the pipeline is the
+// exact version from the repository, which is the PipelineConf.Version of the blocked run, so the conf only needs the
+// name and the version. The latest version is not allowed since it may be changed.
+func Rebuild(ctx context.Context, repository starriver.PipelineRepository, conf starriver.PipelineConf,
+	taskStatuses map[string]starriver.TaskStatus, snapshot starriver.SharedDataStore, initialData map[string]interface{},
 	opts ...core.ContextOption) (starriver.DataContext, starriver.Pipeline, error) {
+	if repository != nil && conf.Version != "" {
+		if conf.Version == starriver.LatestVersion {
+			return nil, nil, fmt.Errorf("the exact version of pipeline %q is required", conf.Name)
+		}
+		var err error
+		if conf, err = getPipeline(repository, conf.Name, conf.Version); err != nil {
+			return nil, nil, err
+		}
+	}
 	pipeline, err := core.BuildPipeline(conf, starriver.PipelineStatusBlocked, taskStatuses)
 	if err != nil {
 		return nil, nil, err
@@ -79,6 +137,10 @@ func NewRiverEngine(options ...Option) *RiverEngine {
 	re := &RiverEngine{
 		WorkerConcurrency: 200,
 		cronClient:        cron.New(cron.WithSeconds()),
+		resuming:          make(map[string]struct{}),
+		schedules:         make(map[ScheduleID]*schedule),
+		live:              make(map[string]*liveRun),
+		ResultCache:       builtin.NewLRUResultCache(0),
 	}
 	for _, option := range options {
 		option(re)
@@ -93,23 +155,36 @@ func SetEventHandler(eventHandler starriver.EventHandler) Option {
 	}
 }
 
-func GetComponents() []*starriver.Component {
-	return registry.GetAllComponents()
+// SetTaskEventHandler fires the lifecycle events of every task of the runs to the handler
+func SetTaskEventHandler(handler starriver.TaskEventHandler) Option {
+	return func(re *RiverEngine) {
+		re.TaskEventHandler = handler
+	}
 }
 
-func (re *RiverEngine) CronRun(spec string, pipelineConf starriver.PipelineConf, data map[string]interface{}) {
-	entryID, err := re.cronClient.AddFunc(spec, func() {
-		pipeline, err := NewPipeline(pipelineConf)
-		if err != nil {
-			logrus.Errorf("[Cron] NewPipeline error %v", err)
-			return
-		}
-		dataContext := NewDataContext(context.Background(), pipeline, data)
-		result := re.Run(dataContext, pipeline)
-		dataContext.Infof("[Cron]spec=%q, pipeline=%q, data=%+v, result=%+v", spec, pipeline.GetName(), data, result)
-	})
-	logrus.Infof("[Cron]AddFunc, spec=%q, pipeline=%q, entryID=%q, err=%v", spec, pipelineConf.Name, entryID, err)
-	re.cronClient.Start()
+// SetTracer reports the spans of every run of the engine to the tracer
+func SetTracer(tracer starriver.Tracer) Option {
+	return func(re *RiverEngine) {
+		re.Tracer = tracer
+	}
+}
+
+// SetMetricsCollector records the metrics of every run of the engine to the collector
+func SetMetricsCollector(collector starriver.MetricsCollector) Option {
+	return func(re *RiverEngine) {
+		re.Metrics = collector
+	}
+}
+
+// SetResultCache stores the outputs of the tasks configured with cache to the cache, the default is an in-memory LRU
+func SetResultCache(cache starriver.ResultCache) Option {
+	return func(re *RiverEngine) {
+		re.ResultCache = cache
+	}
+}
+
+func GetComponents() []*starriver.Component {
+	return registry.GetAllComponents()
 }
 
 func (re *RiverEngine) Run(dataContext starriver.DataContext, pipeline starriver.Pipeline) starriver.Result {
@@ -131,11 +206,64 @@ func (re *RiverEngine) Run(dataContext starriver.DataContext, pipeline starriver
 			re.EventHandler.OnStart(dataContext)
 		}
 	}
+	// the data context is released by the pipeline when the run is done
+	requestID := dataContext.GetRequestID()
+	defer re.register(requestID, core.WithCancel(dataContext))()
+	if re.Metrics != nil {
+		core.WithMetricsCollector(dataContext, re.Metrics)
+	}
+	if re.TaskEventHandler != nil {
+		core.WithTaskEventHandler(dataContext, re.TaskEventHandler)
+	}
+	if re.ResultCache != nil {
+		core.WithResultCache(dataContext, re.ResultCache)
+	}
+	metrics := starriver.MetricsCollectorFromContext(dataContext.Context())
+	start := time.Now()
 	re.Semaphore.Acquire()
 	defer re.Semaphore.Release()
-	return pipeline.Run(dataContext)
+	metrics.ObserveSemaphoreWait(pipeline.GetName(), starriver.SemaphoreEngine, time.Since(start))
+	start = time.Now()
+	if re.Tracer != nil {
+		core.WithTracer(dataContext, re.Tracer)
+	}
+	span := core.StartSpan(dataContext, "pipeline "+pipeline.GetName(),
+		starriver.Attr(starriver.AttrPipelineName, pipeline.GetName()),
+		starriver.Attr(starriver.AttrRequestID, requestID),
+	)
+	re.journalStart(dataContext, pipeline)
+	result := pipeline.Run(dataContext)
+	re.journalEnd(requestID, result)
+	metrics.ObservePipeline(pipeline.GetName(), result.Status, time.Since(start))
+	span.SetAttributes(starriver.Attr(starriver.AttrPipelineStatus, string(result.Status)))
+	span.RecordError(result.Error)
+	span.End()
+	return result
 }
 
+// RunWithOptions runs the tasks selected by the options, run a rebuilt pipeline with From to re-run the tasks against
+// the snapshot, the outputs of the reused tasks are kept.
+func (re *RiverEngine) RunWithOptions(dataContext starriver.DataContext, pipeline starriver.Pipeline, options RunOptions) starriver.Result {
+	if err := core.SelectTasks(pipeline, options.Targets, options.From); err != nil {
+		dataContext.Release()
+		return starriver.Result{Status: starriver.PipelineStatusFailure, Error: err}
+	}
+	return re.Run(dataContext, pipeline)
+}
+
+// Plan is a dry run of the pipeline, it reports the tasks which would run or be skipped and the missing required
+// variables without executing the components, the Simulator components provide the fake outputs for the downstream.
+// The pipeline is used up by the plan, build another one to run.
+func (re *RiverEngine) Plan(dataContext starriver.DataContext, pipeline starriver.Pipeline) (starriver.Plan, error) {
+	return core.Plan(dataContext, pipeline)
+}
+
+// Destroy stops the cron, the schedules are removed and their running runs are cancelled
 func (re *RiverEngine) Destroy() {
 	re.cronClient.Stop()
+	re.scheduleLock.Lock()
+	defer re.scheduleLock.Unlock()
+	for _, s := range re.schedules {
+		re.removeSchedule(s)
+	}
 }
@@ -1,12 +1,14 @@
 package flow
 
 import (
+	"context"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
 
 	"github.com/thanksloving/starriver"
+	"github.com/thanksloving/starriver/registry"
 )
 
 func TestCronRun_MultipleExecution(t *testing.T) {
@@ -35,3 +37,161 @@ func TestCronRun_MultipleExecution(t *testing.T) {
 	// but this test ensures the changed signature and logic don't panic and work as expected.
 	assert.NotNil(t, re.cronClient)
 }
+
+func TestValidate(t *testing.T) {
+	conf := starriver.PipelineConf{
+		Name: "test_validate",
+		Pipeline: []starriver.Task{
+			{
+				ID:   "task1",
+				Name: "TestNode",
+				Config: starriver.TaskConfigure{
+					Params: []starriver.Param{
+						{Name: "Pass", Type: starriver.ParamTypeLiteral, Literal: true},
+						{Name: "NotExist", Type: starriver.ParamTypeLiteral, Literal: 1},
+					},
+				},
+			},
+			{
+				ID:   "task1",
+				Name: "Template",
+				Config: starriver.TaskConfigure{
+					Params: []starriver.Param{
+						{Name: "Template", Type: "unknown"},
+						{Name: "OutputKey", Type: starriver.ParamTypeComplex, Complex: starriver.Params{
+							{Type: starriver.ParamTypeVariable},
+						}},
+					},
+				},
+				Depends: []starriver.Depend{{ID: "task0"}},
+			},
+			{
+				ID:   "task3",
+				Name: "NotExist",
+			},
+			{
+				ID:   "task4",
+				Name: "@all",
+				Depends: []starriver.Depend{
+					{
+						ID:        "task1",
+						Condition: &starriver.Condition{Key: "a", Operator: "~="},
+					},
+				},
+			},
+		},
+	}
+	paths := make([]string, 0)
+	for _, err := range Validate(conf) {
+		paths = append(paths, err.Path)
+	}
+	assert.ElementsMatch(t, []string{
+		"pipeline[0].config.params[1]",
+		"pipeline[1].task",
+		"pipeline[1].config.params[0].type",
+		"pipeline[1].config.params[1].complex[0].variable",
+		"pipeline[1].config.params",
+		"pipeline[1].depends[0].task",
+		"pipeline[2].name",
+		"pipeline[3].name",
+		"pipeline[3].depends[0].condition.operator",
+	}, paths)
+}
+
+func TestValidate_Graph(t *testing.T) {
+	conf := starriver.PipelineConf{
+		Name: "test_validate_graph",
+		Pipeline: []starriver.Task{
+			{ID: "task1", Name: "@any"},
+			{ID: "task2", Name: "@any", Depends: []starriver.Depend{{ID: "task3"}}},
+			{ID: "task3", Name: "@any", Depends: []starriver.Depend{{ID: "task2"}}},
+			{ID: "task4", Name: "@any"},
+		},
+	}
+	errs := Validate(conf)
+	assert.Len(t, errs, 2)
+	assert.Equal(t, "pipeline: cycle: task2, task3", errs[0].Error())
+	assert.Contains(t, errs[1].Message, "multiple roots")
+
+	conf.Pipeline = conf.Pipeline[:1]
+	assert.Empty(t, Validate(conf))
+
+	// the cycle is reported with the other errors, the unknown depend is left out of the graph
+	conf.Pipeline = []starriver.Task{
+		{ID: "task1", Name: "NotExist"},
+		{ID: "task2", Name: "@any", Depends: []starriver.Depend{{ID: "task1"}, {ID: "task3"}}},
+		{ID: "task3", Name: "@any", Depends: []starriver.Depend{{ID: "task2"}, {ID: "task0"}}},
+	}
+	paths := make([]string, 0)
+	for _, err := range Validate(conf) {
+		paths = append(paths, err.Error())
+	}
+	assert.ElementsMatch(t, []string{
+		`pipeline[0].name: can not found component with name "NotExist"`,
+		`pipeline[2].depends[1].task: unknown depend task id "task0"`,
+		"pipeline: cycle: task2, task3",
+	}, paths)
+}
+
+func TestConditionExpr(t *testing.T) {
+	conf := starriver.PipelineConf{
+		Name: "test_condition_expr",
+		Env:  map[string]interface{}{"app": "demo"},
+		Pipeline: []starriver.Task{
+			{ID: "task1", Name: "@any"},
+			{
+				ID:   "task2",
+				Name: "@any",
+				Depends: []starriver.Depend{
+					{ID: "task1", Condition: &starriver.Condition{Expr: `user_level in ["C4","C5"] && score >= 0.8 || env.app == "demo"`}},
+				},
+			},
+			{
+				ID:   "task3",
+				Name: "@any",
+				Depends: []starriver.Depend{
+					{ID: "task1", Condition: &starriver.Condition{Expr: `user_level in ["C4","C5"] && score >= 0.8`}},
+				},
+			},
+		},
+	}
+	pipeline, err := NewPipeline(conf)
+	assert.NoError(t, err)
+	dc := NewDataContext(context.Background(), pipeline, map[string]interface{}{"user_level": "C3", "score": 0.9})
+	re := NewRiverEngine()
+	defer re.Destroy()
+	result := re.Run(dc, pipeline)
+	assert.Equal(t, starriver.PipelineStatusSuccess, result.Status)
+	assert.Equal(t, starriver.TaskStatusSuccess, result.State["task2"])
+	assert.Equal(t, starriver.TaskStatusSkipped, result.State["task3"])
+
+	conf.Pipeline[2].Depends[0].Condition.Expr = `user_level in ["C4"`
+	_, err = NewPipeline(conf)
+	assert.Error(t, err)
+	errs := Validate(conf)
+	assert.Len(t, errs, 1)
+	assert.Equal(t, "pipeline[2].depends[0].condition.expr", errs[0].Path)
+}
+
+func TestNewPipeline_ComponentTimeout(t *testing.T) {
+	componentTimeout, taskTimeout := time.Second, time.Minute
+	registry.Register("TestTimeoutNode", "带默认超时的测试节点", func(id string) starriver.Executable {
+		return registry.GetComponent("TestNode", nil).Executor(id)
+	}, registry.Timeout(&componentTimeout))
+	conf := starriver.PipelineConf{
+		Name: "test_component_timeout",
+		Pipeline: []starriver.Task{
+			{ID: "task1", Name: "TestTimeoutNode"},
+			{ID: "task2", Name: "TestTimeoutNode", Config: starriver.TaskConfigure{Timeout: &taskTimeout},
+				Depends: []starriver.Depend{{ID: "task1"}}},
+		},
+	}
+	// the task without timeout used to dereference the nil timeout of its configure
+	pipeline, err := NewPipeline(conf)
+	if assert.NoError(t, err) {
+		assert.Equal(t, componentTimeout, *pipeline.GetTaskConfigure("task1").Timeout)
+		assert.Equal(t, taskTimeout, *pipeline.GetTaskConfigure("task2").Timeout)
+	}
+	assert.Nil(t, conf.Pipeline[0].Config.Timeout)
+	assert.Equal(t, time.Second, componentTimeout)
+}
@@ -0,0 +1,203 @@
+package flow
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/thanksloving/starriver"
+	"github.com/thanksloving/starriver/internal/core"
+)
+
+// SetJournal records every run of the engine in the journal, so the unfinished runs can be resumed by Recover.
+func SetJournal(journal starriver.RunJournal) Option {
+	return func(re *RiverEngine) {
+		re.Journal = journal
+	}
+}
+
+// SetSharedDataStoreFactory sets the factory of the data store used by the resumed runs, default is NewSharedDataStore.
+// A persistent data store such as NewFileSharedDataStore will recover the shared data which is not in the journal.
+func SetSharedDataStoreFactory(factory func() starriver.SharedDataStore) Option {
+	return func(re *RiverEngine) {
+		re.storeFactory = factory
+	}
+}
+
+func (re *RiverEngine) journalStart(dataContext starriver.DataContext, pipeline starriver.Pipeline) {
+	if re.Journal == nil {
+		return
+	}
+	requestID := dataContext.GetRequestID()
+	conf, ok := core.PipelineConf(pipeline)
+	if !ok {
+		conf.Name = pipeline.GetName()
+	}
+	snapshot, err := dataContext.Marshal()
+	if err != nil {
+		dataContext.Errorf("[Journal] snapshot error %v", err)
+	}
+	if err = re.Journal.Start(starriver.RunRecord{
+		RequestID: requestID,
+		Conf:      conf,
+		Status:    pipeline.GetStatus(),
+		Snapshot:  snapshot,
+	}); err != nil {
+		dataContext.Errorf("[Journal] start error %v", err)
+	}
+	// the outputs are kept by the task hook until the status transition of the task is journaled
+	var outputs sync.Map
+	journaled := core.OnTaskStatus(pipeline, func(taskID string, state starriver.TaskStatus) {
+		var data map[string]interface{}
+		if val, ok := outputs.LoadAndDelete(taskID); ok {
+			data = val.(map[string]interface{})
+		}
+		if err := re.Journal.Task(requestID, taskID, state, data); err != nil {
+			dataContext.Errorf("[Journal] task %q error %v", taskID, err)
+		}
+	})
+	core.AddTaskHook(dataContext, func(dc starriver.DataContext, taskID string, resp starriver.Response) {
+		if dc.Pipeline() != pipeline {
+			// the tasks of the sub pipelines, their ids may be the same as the tasks of the run
+			return
+		}
+		if journaled {
+			if data := resp.GetData(); len(data) > 0 {
+				outputs.Store(taskID, data)
+			}
+			return
+		}
+		if err := re.Journal.Task(requestID, taskID, resp.GetStatus(), resp.GetData()); err != nil {
+			dc.Errorf("[Journal] task %q error %v", taskID, err)
+		}
+	})
+}
+
+func (re *RiverEngine) journalEnd(requestID string, result starriver.Result) {
+	if re.Journal == nil {
+		if result.Status == starriver.PipelineStatusBlocked {
+			logrus.Warnf("[Journal] run %q is blocked without the journal, it can not be resumed by Signal or Resume", requestID)
+		}
+		return
+	}
+	if err := re.Journal.End(requestID, result.Status, result.Snapshot); err != nil {
+		logrus.Errorf("[Journal] end %q error %v", requestID, err)
+	}
+}
+
+func (re *RiverEngine) newSharedDataStore() starriver.SharedDataStore {
+	if re.storeFactory != nil {
+		return re.storeFactory()
+	}
+	return NewSharedDataStore()
+}
+
+// rebuild restores the run from the journal, the tasks of blocked or init status will be re-run.
+func (re *RiverEngine) rebuild(ctx context.Context, run *starriver.RunRecord,
+	opts ...core.ContextOption) (starriver.DataContext, starriver.Pipeline, error) {
+	store := re.newSharedDataStore()
+	if len(run.Snapshot) > 0 {
+		if err := store.Unmarshal(run.Snapshot); err != nil {
+			return nil, nil, fmt.Errorf("restore snapshot of %q error: %v", run.RequestID, err)
+		}
+	}
+	for taskID, data := range run.NodeData {
+		store.SetCurrentNodeData(ctx, taskID, data)
+	}
+	taskStatuses := make(map[string]starriver.TaskStatus, len(run.State))
+	for taskID, status := range run.State {
+		taskStatuses[taskID] = status
+	}
+	// the request id of data context is prefixed by the pipeline name
+	opts = append(opts, SetRequestID(strings.TrimPrefix(run.RequestID, run.Conf.Name+"#")))
+	return Rebuild(ctx, re.Repository, run.Conf, taskStatuses, store, nil, opts...)
+}
+
+// Recover resumes the unfinished runs of the journal, e.g. the process crashed during the runs, and waits for them done.
+// The tasks finished before are not executed again, the others are executed at least once.
+func (re *RiverEngine) Recover(ctx context.Context, opts ...core.ContextOption) (map[string]starriver.Result, error) {
+	if re.Journal == nil {
+		return nil, fmt.Errorf("journal is not set")
+	}
+	runs, err := re.Journal.Unfinished()
+	if err != nil {
+		return nil, err
+	}
+	var (
+		wg      sync.WaitGroup
+		lock    sync.Mutex
+		results = make(map[string]starriver.Result, len(runs))
+	)
+	for _, run := range runs {
+		dataContext, pipeline, err := re.rebuild(ctx, run, opts...)
+		if err != nil {
+			logrus.Errorf("[Recover] rebuild %q error %v", run.RequestID, err)
+			result := starriver.Result{Status: starriver.PipelineStatusFailure, State: run.State, Error: err}
+			// the run can never be resumed, end it to avoid recovering again
+			re.journalEnd(run.RequestID, result)
+			lock.Lock()
+			results[run.RequestID] = result
+			lock.Unlock()
+			continue
+		}
+		wg.Add(1)
+		go func(requestID string) {
+			defer wg.Done()
+			result := re.Run(dataContext, pipeline)
+			lock.Lock()
+			defer lock.Unlock()
+			results[requestID] = result
+		}(run.RequestID)
+	}
+	wg.Wait()
+	return results, nil
+}
+
+// Resume re-runs the blocked run recorded in the journal, the data is put into the shared data before the run,
+// e.g. the approval of a human. The tasks finished before are not executed again.
+func (re *RiverEngine) Resume(ctx context.Context, requestID string, data map[string]interface{}) (starriver.Result, error) {
+	if re.Journal == nil {
+		return starriver.Result{}, fmt.Errorf("journal is not set")
+	}
+	if !re.markResuming(requestID) {
+		return starriver.Result{}, fmt.Errorf("run %q is resuming", requestID)
+	}
+	defer re.unmarkResuming(requestID)
+	run, err := re.Journal.Get(requestID)
+	if err != nil {
+		return starriver.Result{}, err
+	}
+	if run == nil {
+		return starriver.Result{}, fmt.Errorf("run %q not found", requestID)
+	}
+	if !run.Finished || run.Status != starriver.PipelineStatusBlocked {
+		return starriver.Result{}, fmt.Errorf("run %q is not blocked, status=%s", requestID, run.Status)
+	}
+	dataContext, pipeline, err := re.rebuild(ctx, run)
+	if err != nil {
+		return starriver.Result{}, err
+	}
+	for key, val := range data {
+		dataContext.Set(key, val)
+	}
+	return re.Run(dataContext, pipeline), nil
+}
+
+func (re *RiverEngine) markResuming(requestID string) bool {
+	re.resumingLock.Lock()
+	defer re.resumingLock.Unlock()
+	if _, ok := re.resuming[requestID]; ok {
+		return false
+	}
+	re.resuming[requestID] = struct{}{}
+	return true
+}
+
+func (re *RiverEngine) unmarkResuming(requestID string) {
+	re.resumingLock.Lock()
+	defer re.resumingLock.Unlock()
+	delete(re.resuming, requestID)
+}
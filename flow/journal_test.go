@@ -0,0 +1,284 @@
+package flow
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/thanksloving/starriver"
+	"github.com/thanksloving/starriver/helper"
+	"github.com/thanksloving/starriver/registry"
+)
+
+func journalTestConf() starriver.PipelineConf {
+	return starriver.PipelineConf{
+		Name:   "test_journal",
+		Result: []string{"out"},
+		Pipeline: []starriver.Task{
+			{
+				ID:   "task1",
+				Name: "TestNode",
+				Config: starriver.TaskConfigure{
+					Params: []starriver.Param{{Name: "Pass", Type: starriver.ParamTypeLiteral, Literal: true}},
+				},
+			},
+			{
+				ID:   "task2",
+				Name: "Template",
+				Config: starriver.TaskConfigure{
+					Params: []starriver.Param{
+						{Name: "Template", Type: starriver.ParamTypeLiteral, Literal: `{{ str "name" }}`},
+						{Name: "OutputKey", Type: starriver.ParamTypeLiteral, Literal: "out"},
+						{Name: "Shared", Type: starriver.ParamTypeLiteral, Literal: true},
+					},
+				},
+				Depends: []starriver.Depend{{ID: "task1"}},
+			},
+		},
+	}
+}
+
+func TestRun_Journal(t *testing.T) {
+	journal := NewMemoryJournal()
+	re := NewRiverEngine(SetJournal(journal))
+	defer re.Destroy()
+
+	pipeline, err := NewPipeline(journalTestConf())
+	assert.NoError(t, err)
+	dc := NewDataContext(context.Background(), pipeline, map[string]interface{}{"name": "jimmy"}, SetRequestID("1"))
+	result := re.Run(dc, pipeline)
+	assert.Equal(t, starriver.PipelineStatusSuccess, result.Status)
+
+	run, err := journal.Get("test_journal#1")
+	assert.NoError(t, err)
+	assert.True(t, run.Finished)
+	assert.Equal(t, starriver.PipelineStatusSuccess, run.Status)
+	assert.Equal(t, starriver.TaskStatusSuccess, run.State["task1"])
+	assert.Equal(t, starriver.TaskStatusSuccess, run.State["task2"])
+	assert.Equal(t, "jimmy", run.NodeData["task2"]["out"])
+
+	unfinished, err := journal.Unfinished()
+	assert.NoError(t, err)
+	assert.Empty(t, unfinished)
+}
+
+func TestRecover(t *testing.T) {
+	journal, err := NewFileJournal(t.TempDir())
+	assert.NoError(t, err)
+
+	// the process crashed after task1 is done
+	store := NewSharedDataStore()
+	store.Set(context.Background(), "name", "jimmy")
+	snapshot, err := store.Marshal()
+	assert.NoError(t, err)
+	assert.NoError(t, journal.Start(starriver.RunRecord{
+		RequestID: "test_journal#1",
+		Conf:      journalTestConf(),
+		Status:    starriver.PipelineStatusInit,
+		Snapshot:  snapshot,
+	}))
+	assert.NoError(t, journal.Task("test_journal#1", "task1", starriver.TaskStatusSuccess, nil))
+
+	re := NewRiverEngine(SetJournal(journal))
+	defer re.Destroy()
+	results, err := re.Recover(context.Background())
+	assert.NoError(t, err)
+	assert.Len(t, results, 1)
+	result := results["test_journal#1"]
+	assert.Equal(t, starriver.PipelineStatusSuccess, result.Status)
+	assert.Equal(t, "jimmy", result.Data["out"])
+	// task1 is not executed again
+	assert.NotContains(t, result.Attempts, "task1")
+	assert.Equal(t, 1, result.Attempts["task2"])
+
+	unfinished, err := journal.Unfinished()
+	assert.NoError(t, err)
+	assert.Empty(t, unfinished)
+}
+
+type (
+	// taskJournal records the Task calls of the run journal
+	taskJournal struct {
+		starriver.RunJournal
+		lock  sync.Mutex
+		tasks []string
+	}
+
+	// childComponent runs the journal test pipeline as a sub pipeline, with the data context of the task
+	childComponent struct {
+		helper.Skeleton
+	}
+)
+
+func (j *taskJournal) Task(requestID, taskID string, status starriver.TaskStatus, data map[string]interface{}) error {
+	j.lock.Lock()
+	j.tasks = append(j.tasks, taskID+":"+string(status))
+	j.lock.Unlock()
+	return j.RunJournal.Task(requestID, taskID, status, data)
+}
+
+func (c *childComponent) Execute(dataContext starriver.DataContext, _ interface{}) starriver.Response {
+	conf := journalTestConf()
+	conf.Name = "test_journal_child"
+	pipeline, err := NewPipeline(conf)
+	if err != nil {
+		return helper.NewErrorResponse(err)
+	}
+	result := pipeline.Run(NewDataContext(dataContext.Context(), pipeline, map[string]interface{}{"name": "child"}))
+	if result.Error != nil {
+		return helper.NewErrorResponse(result.Error)
+	}
+	return helper.NewSuccessDataResponse(result.Data)
+}
+
+func TestRun_JournalSubPipeline(t *testing.T) {
+	registry.Register("TestChild", "执行子流程的节点", func(id string) starriver.Executable {
+		return &childComponent{Skeleton: helper.NewSkeleton(id)}
+	})
+	journal := &taskJournal{RunJournal: NewMemoryJournal()}
+	re := NewRiverEngine(SetJournal(journal))
+	defer re.Destroy()
+
+	conf := starriver.PipelineConf{
+		Name: "test_journal_parent",
+		Pipeline: []starriver.Task{
+			{ID: "step1", Name: "TestChild"},
+			{
+				// the same id as the task of the sub pipeline
+				ID:      "task2",
+				Name:    "TestNode",
+				Config:  starriver.TaskConfigure{Params: []starriver.Param{{Name: "Pass", Type: starriver.ParamTypeLiteral, Literal: true}}},
+				Depends: []starriver.Depend{{ID: "step1"}},
+			},
+		},
+	}
+	pipeline, err := NewPipeline(conf)
+	assert.NoError(t, err)
+	result := re.Run(NewDataContext(context.Background(), pipeline, nil, SetRequestID("1")), pipeline)
+	assert.NoError(t, result.Error)
+	assert.Equal(t, starriver.PipelineStatusSuccess, result.Status)
+	assert.Equal(t, []string{"step1:success", "task2:success"}, journal.tasks)
+
+	run, err := journal.Get("test_journal_parent#1")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]starriver.TaskStatus{
+		"step1": starriver.TaskStatusSuccess,
+		"task2": starriver.TaskStatusSuccess,
+	}, run.State)
+	assert.NotContains(t, run.NodeData, "task1")
+}
+
+func TestRun_JournalSkippedTask(t *testing.T) {
+	journal := &taskJournal{RunJournal: NewMemoryJournal()}
+	re := NewRiverEngine(SetJournal(journal))
+	defer re.Destroy()
+
+	pass := starriver.TaskConfigure{Params: []starriver.Param{{Name: "Pass", Type: starriver.ParamTypeLiteral, Literal: true}}}
+	skip := pass
+	skip.SkipExecution = true
+	conf := starriver.PipelineConf{
+		Name: "test_journal_skipped",
+		Pipeline: []starriver.Task{
+			{ID: "task1", Name: "TestNode", Config: pass},
+			{ID: "task2", Name: "TestNode", Config: skip, Depends: []starriver.Depend{{ID: "task1"}}},
+			{
+				ID:     "task3",
+				Name:   "TestNode",
+				Config: pass,
+				Depends: []starriver.Depend{
+					{ID: "task1", Condition: &starriver.Condition{Key: "score", Value: 60, Operator: starriver.ConditionGT}},
+				},
+			},
+		},
+	}
+	pipeline, err := NewPipeline(conf)
+	assert.NoError(t, err)
+	result := re.Run(NewDataContext(context.Background(), pipeline, map[string]interface{}{"score": 10}, SetRequestID("1")), pipeline)
+	assert.NoError(t, result.Error)
+	assert.ElementsMatch(t, []string{"task1:success", "task2:skipped", "task3:skipped"}, journal.tasks)
+
+	run, err := journal.Get("test_journal_skipped#1")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]starriver.TaskStatus{
+		"task1": starriver.TaskStatusSuccess,
+		"task2": starriver.TaskStatusSkipped,
+		"task3": starriver.TaskStatusSkipped,
+	}, run.State)
+}
+
+type (
+	counterComponent struct {
+		helper.Skeleton
+	}
+
+	increaseComponent struct {
+		helper.Skeleton
+	}
+
+	increaseParam struct {
+		Count int
+	}
+)
+
+func (c *counterComponent) Execute(dataContext starriver.DataContext, _ interface{}) starriver.Response {
+	dataContext.Set("count", 3)
+	return helper.NewSuccessDataResponse(map[string]interface{}{"count": 3})
+}
+
+func (c *increaseComponent) ParameterNew() interface{} {
+	return &increaseParam{}
+}
+
+func (c *increaseComponent) Execute(dataContext starriver.DataContext, param interface{}) starriver.Response {
+	dataContext.Set("next", param.(*increaseParam).Count+1)
+	return helper.NewSuccessResponse()
+}
+
+func TestResume_NumberOutput(t *testing.T) {
+	registry.Register("TestCounter", "输出整数的测试节点", func(id string) starriver.Executable {
+		return &counterComponent{Skeleton: helper.NewSkeleton(id)}
+	})
+	registry.Register("TestIncrease", "整数参数加一的测试节点", func(id string) starriver.Executable {
+		return &increaseComponent{Skeleton: helper.NewSkeleton(id)}
+	})
+	conf := starriver.PipelineConf{
+		Name:   "test_number_output",
+		Result: []string{"next"},
+		Pipeline: []starriver.Task{
+			{ID: "count", Name: "TestCounter"},
+			{
+				ID:   "approve",
+				Name: "WaitForSignal",
+				Config: starriver.TaskConfigure{
+					Params: []starriver.Param{{Name: "Signal", Type: starriver.ParamTypeLiteral, Literal: "approval"}},
+				},
+				Depends: []starriver.Depend{{ID: "count"}},
+			},
+			{
+				ID:   "increase",
+				Name: "TestIncrease",
+				Config: starriver.TaskConfigure{
+					Params: []starriver.Param{{Name: "Count", Type: starriver.ParamTypeVariable, Variable: "count", Required: true}},
+				},
+				Depends: []starriver.Depend{{ID: "approve"}},
+			},
+		},
+	}
+	journal, err := NewFileJournal(t.TempDir())
+	assert.NoError(t, err)
+	re := NewRiverEngine(SetJournal(journal))
+	defer re.Destroy()
+
+	pipeline, err := NewPipeline(conf)
+	assert.NoError(t, err)
+	result := re.Run(NewDataContext(context.Background(), pipeline, nil, SetRequestID("1")), pipeline)
+	assert.Equal(t, starriver.PipelineStatusBlocked, result.Status)
+
+	// the int output is restored from the journal as a float64, it's still assigned to the int parameter
+	result, err = re.Signal("test_number_output#1", "approval", "approved")
+	assert.NoError(t, err)
+	assert.Equal(t, starriver.PipelineStatusSuccess, result.Status)
+	assert.Equal(t, 4, result.Data["next"])
+}
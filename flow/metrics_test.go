@@ -0,0 +1,32 @@
+package flow
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/thanksloving/starriver"
+)
+
+func TestRun_Metrics(t *testing.T) {
+	collector := NewPrometheusCollector()
+	re := NewRiverEngine(SetMetricsCollector(collector))
+	defer re.Destroy()
+
+	pipeline, err := NewPipeline(journalTestConf())
+	assert.NoError(t, err)
+	dc := NewDataContext(context.Background(), pipeline, map[string]interface{}{"name": "jimmy"})
+	result := re.Run(dc, pipeline)
+	assert.Equal(t, starriver.PipelineStatusSuccess, result.Status)
+
+	rec := httptest.NewRecorder()
+	collector.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	body := rec.Body.String()
+	assert.Contains(t, body, `starriver_pipeline_runs_total{pipeline="test_journal",status="success"} 1`)
+	assert.Contains(t, body, `starriver_task_runs_total{pipeline="test_journal",task="task1",component="TestNode",status="success",failure_level="0"} 1`)
+	assert.Contains(t, body, `starriver_task_runs_total{pipeline="test_journal",task="task2",component="Template",status="success",failure_level="0"} 1`)
+	assert.Contains(t, body, `starriver_semaphore_wait_seconds_count{pipeline="test_journal",semaphore="engine"} 1`)
+	assert.Contains(t, body, `starriver_semaphore_wait_seconds_count{pipeline="test_journal",semaphore="pipeline"} 2`)
+}
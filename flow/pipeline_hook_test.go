@@ -0,0 +1,121 @@
+package flow
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/thanksloving/starriver"
+	"github.com/thanksloving/starriver/helper"
+	"github.com/thanksloving/starriver/registry"
+)
+
+type (
+	hookComponent struct {
+		helper.Skeleton
+		seen *hookRecorder
+	}
+
+	hookRecorder struct {
+		lock sync.Mutex
+		data map[string]map[string]interface{}
+	}
+)
+
+func (h *hookComponent) Execute(dataContext starriver.DataContext, _ interface{}) starriver.Response {
+	h.seen.lock.Lock()
+	defer h.seen.lock.Unlock()
+	data := make(map[string]interface{})
+	for _, key := range []string{starriver.HookKeyStatus, starriver.HookKeyState, starriver.HookKeyError} {
+		if val, ok := dataContext.Get(key); ok {
+			data[key] = val
+		}
+	}
+	// the hooks run even if the run was stopped
+	data["cancelled"] = dataContext.Err() != nil
+	h.seen.data[h.ID()] = data
+	return helper.NewSuccessResponse()
+}
+
+func TestRun_PipelineHooks(t *testing.T) {
+	seen := &hookRecorder{data: make(map[string]map[string]interface{})}
+	registry.Register("TestHook", "记录流程结果的节点", func(id string) starriver.Executable {
+		return &hookComponent{Skeleton: helper.NewSkeleton(id), seen: seen}
+	})
+	conf := starriver.PipelineConf{
+		Name: "test_pipeline_hooks",
+		Pipeline: []starriver.Task{
+			{
+				ID:     "task1",
+				Name:   "TestNode",
+				Config: starriver.TaskConfigure{Params: []starriver.Param{{Name: "Pass", Type: starriver.ParamTypeLiteral, Literal: true}}},
+			},
+			{
+				ID:      "task2",
+				Name:    "Wait",
+				Config:  starriver.TaskConfigure{Params: []starriver.Param{{Name: "WaitingTime", Type: starriver.ParamTypeLiteral, Literal: "10ms"}}},
+				Depends: []starriver.Depend{{ID: "task1"}},
+			},
+		},
+		OnSuccess: []starriver.Task{{ID: "notify_success", Name: "TestHook"}},
+		OnFailure: []starriver.Task{{ID: "notify_failure", Name: "TestHook"}},
+		Finally:   []starriver.Task{{ID: "cleanup", Name: "TestHook"}},
+	}
+	assert.Empty(t, Validate(conf))
+	pipeline, err := NewPipeline(conf)
+	assert.NoError(t, err)
+	result := NewRiverEngine().Run(NewDataContext(context.Background(), pipeline, nil), pipeline)
+	assert.Equal(t, starriver.PipelineStatusSuccess, result.Status)
+	if assert.Len(t, result.Hooks, 2) {
+		assert.Equal(t, "notify_success", result.Hooks[0].TaskID)
+		assert.Equal(t, "cleanup", result.Hooks[1].TaskID)
+		assert.Equal(t, starriver.TaskStatusSuccess, result.Hooks[1].Status)
+	}
+	assert.Equal(t, "success", seen.data["cleanup"][starriver.HookKeyStatus])
+	assert.NotContains(t, seen.data["cleanup"], starriver.HookKeyError)
+	assert.NotContains(t, seen.data, "notify_failure")
+
+	// task1 fails and aborts the pipeline by Stop
+	seen.data = make(map[string]map[string]interface{})
+	conf.Pipeline[0].Config.Params[0].Literal = false
+	conf.Pipeline[0].Config.AbortIfError = true
+	pipeline, err = NewPipeline(conf)
+	assert.NoError(t, err)
+	result = NewRiverEngine().Run(NewDataContext(context.Background(), pipeline, nil), pipeline)
+	assert.Equal(t, starriver.PipelineStatusFailure, result.Status)
+	if assert.Len(t, result.Hooks, 2) {
+		assert.Equal(t, "notify_failure", result.Hooks[0].TaskID)
+		assert.Equal(t, "cleanup", result.Hooks[1].TaskID)
+	}
+	assert.Equal(t, map[string]interface{}{
+		starriver.HookKeyStatus: "failure",
+		starriver.HookKeyState: map[string]starriver.TaskStatus{
+			"task1": starriver.TaskStatusFailure,
+			"task2": starriver.TaskStatusInit,
+		},
+		starriver.HookKeyError: result.Error.Error(),
+		"cancelled":            false,
+	}, seen.data["notify_failure"])
+	assert.NotContains(t, seen.data, "notify_success")
+}
+
+func TestValidate_PipelineHooks(t *testing.T) {
+	conf := starriver.PipelineConf{
+		Name:      "test_pipeline_hooks",
+		Pipeline:  []starriver.Task{{ID: "task1", Name: "TestNode"}},
+		OnSuccess: []starriver.Task{{ID: "task1", Name: "TestNode"}},
+		OnFailure: []starriver.Task{{ID: "notify", Name: "@any"}},
+		Finally:   []starriver.Task{{ID: "notify", Name: "TestNode", Depends: []starriver.Depend{{ID: "task1"}}}},
+	}
+	errs := Validate(conf)
+	if assert.Len(t, errs, 4) {
+		assert.Equal(t, "on_success[0].task", errs[0].Path)
+		assert.Equal(t, "on_failure[0].name", errs[1].Path)
+		assert.Equal(t, "finally[0].task", errs[2].Path)
+		assert.Equal(t, "finally[0].depends", errs[3].Path)
+	}
+	_, err := NewPipeline(conf)
+	assert.Error(t, err)
+}
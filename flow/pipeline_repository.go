@@ -0,0 +1,47 @@
+package flow
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/thanksloving/starriver"
+	"github.com/thanksloving/starriver/internal/core"
+)
+
+// SetPipelineRepository runs the pipelines by their names and versions from the repository, see RunByName.
+// The blocked or unfinished runs started by RunByName are rebuilt with the exact version they started with.
+func SetPipelineRepository(repository starriver.PipelineRepository) Option {
+	return func(re *RiverEngine) {
+		re.Repository = repository
+	}
+}
+
+// getPipeline gets the pipeline of the version from the repository, the latest one if the version is empty
+func getPipeline(repository starriver.PipelineRepository, name, version string) (starriver.PipelineConf, error) {
+	def, err := repository.Get(name, version)
+	if err != nil {
+		return starriver.PipelineConf{}, err
+	}
+	if def == nil {
+		return starriver.PipelineConf{}, fmt.Errorf("pipeline %q version %q not found", name, version)
+	}
+	return def.Conf, nil
+}
+
+// RunByName runs the pipeline of the version from the repository, the latest one if the version is empty or LatestVersion.
+func (re *RiverEngine) RunByName(ctx context.Context, name, version string, data map[string]interface{},
+	opts ...core.ContextOption) (starriver.Result, error) {
+	if re.Repository == nil {
+		return starriver.Result{}, fmt.Errorf("pipeline repository is not set")
+	}
+	conf, err := getPipeline(re.Repository, name, version)
+	if err != nil {
+		return starriver.Result{}, err
+	}
+	pipeline, err := NewPipeline(conf)
+	if err != nil {
+		return starriver.Result{}, err
+	}
+	dataContext := NewDataContext(ctx, pipeline, data, opts...)
+	return re.Run(dataContext, pipeline), nil
+}
@@ -0,0 +1,78 @@
+package flow
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/thanksloving/starriver"
+	"github.com/thanksloving/starriver/internal/core"
+)
+
+func approvalPipeline(template string) starriver.PipelineConf {
+	return starriver.PipelineConf{
+		Result: []string{"out"},
+		Pipeline: []starriver.Task{
+			{
+				ID:   "approve",
+				Name: "WaitForSignal",
+				Config: starriver.TaskConfigure{
+					Params: []starriver.Param{{Name: "Signal", Type: starriver.ParamTypeLiteral, Literal: "approval"}},
+				},
+			},
+			{
+				ID:   "notify",
+				Name: "Template",
+				Config: starriver.TaskConfigure{
+					Params: []starriver.Param{
+						{Name: "Template", Type: starriver.ParamTypeLiteral, Literal: template},
+						{Name: "OutputKey", Type: starriver.ParamTypeLiteral, Literal: "out"},
+						{Name: "Shared", Type: starriver.ParamTypeLiteral, Literal: true},
+					},
+				},
+				Depends: []starriver.Depend{{ID: "approve"}},
+			},
+		},
+	}
+}
+
+func TestRunByName(t *testing.T) {
+	repository := NewMemoryPipelineRepository()
+	re := NewRiverEngine(SetJournal(NewMemoryJournal()), SetPipelineRepository(repository))
+	defer re.Destroy()
+
+	_, err := re.RunByName(context.Background(), "approval", "", nil)
+	assert.Error(t, err)
+
+	assert.NoError(t, repository.Put("approval", "v1", approvalPipeline(`v1 {{ str "signal.approval" }}`)))
+	result, err := re.RunByName(context.Background(), "approval", "", nil, SetRequestID("1"))
+	assert.NoError(t, err)
+	assert.Equal(t, starriver.PipelineStatusBlocked, result.Status)
+
+	// the blocked run is resumed with the version it started with
+	assert.NoError(t, repository.Put("approval", "v2", approvalPipeline(`v2 {{ str "signal.approval" }}`)))
+	result, err = re.Signal("approval#1", "approval", "approved")
+	assert.NoError(t, err)
+	assert.Equal(t, starriver.PipelineStatusSuccess, result.Status)
+	assert.Equal(t, "v1 approved", result.Data["out"])
+
+	result, err = re.RunByName(context.Background(), "approval", starriver.LatestVersion, map[string]interface{}{starriver.SignalKey("approval"): "ok"})
+	assert.NoError(t, err)
+	assert.Equal(t, "v2 ok", result.Data["out"])
+
+	result, err = re.RunByName(context.Background(), "approval", "v1", map[string]interface{}{starriver.SignalKey("approval"): "ok"})
+	assert.NoError(t, err)
+	assert.Equal(t, "v1 ok", result.Data["out"])
+
+	_, _, err = Rebuild(context.Background(), repository, starriver.PipelineConf{Name: "approval", Version: starriver.LatestVersion}, nil, nil, nil)
+	assert.Error(t, err)
+	_, _, err = Rebuild(context.Background(), repository, starriver.PipelineConf{Name: "approval", Version: "v3"}, nil, nil, nil)
+	assert.Error(t, err)
+	// the conf of the repository is used instead of the given one
+	_, pipeline, err := Rebuild(context.Background(), repository, starriver.PipelineConf{Name: "approval", Version: "v1"}, map[string]starriver.TaskStatus{}, nil, nil)
+	assert.NoError(t, err)
+	conf, _ := core.PipelineConf(pipeline)
+	assert.Equal(t, "v1", conf.Version)
+	assert.Len(t, conf.Pipeline, 2)
+}
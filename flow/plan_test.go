@@ -0,0 +1,91 @@
+package flow
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/thanksloving/starriver"
+	"github.com/thanksloving/starriver/helper"
+	"github.com/thanksloving/starriver/registry"
+)
+
+type scoreComponent struct {
+	helper.Skeleton
+	executed *int32
+}
+
+func (s *scoreComponent) Execute(starriver.DataContext, interface{}) starriver.Response {
+	atomic.AddInt32(s.executed, 1)
+	return helper.NewSuccessDataResponse(map[string]interface{}{"score": 10})
+}
+
+func (s *scoreComponent) Simulate(starriver.DataContext, interface{}) map[string]interface{} {
+	return map[string]interface{}{"score": 80}
+}
+
+func TestPlan(t *testing.T) {
+	var executed int32
+	registry.Register("TestScore", "可模拟输出的节点", func(id string) starriver.Executable {
+		return &scoreComponent{Skeleton: helper.NewSkeleton(id), executed: &executed}
+	})
+	pass := starriver.TaskConfigure{
+		Params: []starriver.Param{{Name: "Pass", Type: starriver.ParamTypeLiteral, Literal: true}},
+	}
+	conf := starriver.PipelineConf{
+		Name: "test_plan",
+		Pipeline: []starriver.Task{
+			{ID: "score", Name: "TestScore"},
+			{
+				ID:      "high",
+				Name:    "TestNode",
+				Config:  pass,
+				Depends: []starriver.Depend{{ID: "score", Condition: &starriver.Condition{Key: "score", Value: 60, Operator: starriver.ConditionGT}}},
+			},
+			{
+				ID:      "low",
+				Name:    "TestNode",
+				Config:  pass,
+				Depends: []starriver.Depend{{ID: "score", Condition: &starriver.Condition{Key: "score", Value: 60, Operator: starriver.ConditionLE}}},
+			},
+			{ID: "after_low", Name: "TestNode", Config: pass, Depends: []starriver.Depend{{ID: "low"}}},
+			{
+				ID:   "greet",
+				Name: "Template",
+				Config: starriver.TaskConfigure{Params: []starriver.Param{
+					{Name: "Template", Type: starriver.ParamTypeVariable, Variable: "greeting", Required: true},
+					{Name: "OutputKey", Type: starriver.ParamTypeLiteral, Literal: "out"},
+					{Name: "Shared", Type: starriver.ParamTypeLiteral, Literal: false},
+				}},
+				Depends: []starriver.Depend{{ID: "high"}},
+			},
+		},
+	}
+	re := NewRiverEngine()
+	defer re.Destroy()
+
+	pipeline, err := NewPipeline(conf)
+	assert.NoError(t, err)
+	plan, err := re.Plan(NewDataContext(context.Background(), pipeline, nil), pipeline)
+	assert.NoError(t, err)
+	assert.Zero(t, atomic.LoadInt32(&executed))
+	assert.Equal(t, []string{"greet", "high", "score"}, plan.Run)
+	assert.Equal(t, []string{"after_low", "low"}, plan.Skip)
+	assert.Empty(t, plan.Fail)
+	assert.Equal(t, map[string][]string{"greet": {"greeting"}}, plan.Missing)
+	reasons := make(map[string]starriver.SkipReason)
+	for _, record := range plan.Tasks {
+		reasons[record.TaskID] = record.SkipReason
+	}
+	assert.Equal(t, starriver.SkipReasonConditionNotMatch, reasons["low"])
+	assert.Equal(t, starriver.SkipReasonUpstreamSkipped, reasons["after_low"])
+
+	pipeline, err = NewPipeline(conf)
+	assert.NoError(t, err)
+	plan, err = re.Plan(NewDataContext(context.Background(), pipeline, map[string]interface{}{"greeting": "hi"}), pipeline)
+	assert.NoError(t, err)
+	assert.Empty(t, plan.Missing)
+	assert.Zero(t, atomic.LoadInt32(&executed))
+}
@@ -0,0 +1,81 @@
+package flow
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/thanksloving/starriver"
+)
+
+func TestRun_Report(t *testing.T) {
+	pass := starriver.TaskConfigure{
+		Params: []starriver.Param{{Name: "Pass", Type: starriver.ParamTypeLiteral, Literal: true}},
+	}
+	conf := starriver.PipelineConf{
+		Name: "test_report",
+		Pipeline: []starriver.Task{
+			{ID: "task1", Name: "TestNode", Config: pass},
+			{
+				ID:      "task2",
+				Name:    "TestNode",
+				Config:  pass,
+				Depends: []starriver.Depend{{ID: "task1", Condition: &starriver.Condition{Key: "score", Value: 60, Operator: starriver.ConditionGT}}},
+			},
+			{
+				ID:      "task3",
+				Name:    "TestNode",
+				Config:  starriver.TaskConfigure{Params: []starriver.Param{{Name: "Pass", Type: starriver.ParamTypeLiteral, Literal: false}}},
+				Depends: []starriver.Depend{{ID: "task1"}},
+			},
+			{ID: "task5", Name: "TestNode", Config: pass, Depends: []starriver.Depend{{ID: "task3"}}},
+			{
+				ID:      "task4",
+				Name:    "TestNode",
+				Config:  starriver.TaskConfigure{SkipExecution: true},
+				Depends: []starriver.Depend{{ID: "task1"}},
+			},
+		},
+	}
+	pipeline, err := NewPipeline(conf)
+	assert.NoError(t, err)
+	dc := NewDataContext(context.Background(), pipeline, map[string]interface{}{"score": 10})
+	result := NewRiverEngine().Run(dc, pipeline)
+	assert.Equal(t, starriver.PipelineStatusFailure, result.Status)
+
+	report := result.Report
+	assert.NotNil(t, report)
+	assert.False(t, report.EndedAt.Before(report.StartedAt))
+	assert.Len(t, report.Tasks, 5)
+	records := make(map[string]starriver.TaskRecord)
+	for _, record := range report.Tasks {
+		records[record.TaskID] = record
+	}
+
+	task1 := records["task1"]
+	assert.Equal(t, starriver.TaskStatusSuccess, task1.Status)
+	assert.Equal(t, 1, task1.Attempts)
+	assert.Empty(t, task1.SkipReason)
+	assert.False(t, task1.StartedAt.Before(task1.ReadyAt))
+	assert.False(t, task1.EndedAt.Before(task1.StartedAt))
+
+	task2 := records["task2"]
+	assert.Equal(t, starriver.SkipReasonConditionNotMatch, task2.SkipReason)
+	assert.Equal(t, &starriver.FailedCondition{Depend: "task1", Condition: "score > 60"}, task2.FailedCondition)
+	assert.Equal(t, "condition not match", task2.Error)
+	assert.True(t, task2.StartedAt.IsZero())
+
+	assert.Equal(t, starriver.TaskStatusFailure, records["task3"].Status)
+	assert.Equal(t, starriver.FailureLevelError, records["task3"].FailureLevel)
+	assert.Equal(t, starriver.SkipReasonUpstreamFailed, records["task5"].SkipReason)
+	assert.Equal(t, starriver.SkipReasonSkipExecution, records["task4"].SkipReason)
+	assert.Equal(t, starriver.TaskStatusSkipped, records["task4"].Status)
+
+	bs, err := json.Marshal(report)
+	assert.NoError(t, err)
+	var decoded starriver.Report
+	assert.NoError(t, json.Unmarshal(bs, &decoded))
+	assert.Len(t, decoded.Tasks, 5)
+}
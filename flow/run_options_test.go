@@ -0,0 +1,117 @@
+package flow
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/thanksloving/starriver"
+	"github.com/thanksloving/starriver/helper"
+	"github.com/thanksloving/starriver/registry"
+)
+
+type (
+	executedComponent struct {
+		helper.Skeleton
+		executed *executedRecorder
+	}
+
+	executedRecorder struct {
+		lock  sync.Mutex
+		tasks []string
+	}
+)
+
+func (e *executedComponent) Execute(dataContext starriver.DataContext, _ interface{}) starriver.Response {
+	e.executed.lock.Lock()
+	defer e.executed.lock.Unlock()
+	e.executed.tasks = append(e.executed.tasks, e.ID())
+	return helper.NewSuccessDataResponse(map[string]interface{}{"out": e.ID()})
+}
+
+func (r *executedRecorder) reset() []string {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	tasks := r.tasks
+	r.tasks = nil
+	return tasks
+}
+
+func TestRunWithOptions(t *testing.T) {
+	executed := &executedRecorder{}
+	registry.Register("TestExecuted", "记录执行的节点", func(id string) starriver.Executable {
+		return &executedComponent{Skeleton: helper.NewSkeleton(id), executed: executed}
+	})
+	conf := starriver.PipelineConf{
+		Name: "test_run_options",
+		Pipeline: []starriver.Task{
+			{ID: "a", Name: "TestExecuted"},
+			{ID: "b", Name: "TestExecuted", Depends: []starriver.Depend{{ID: "a"}}},
+			{ID: "c", Name: "TestExecuted", Depends: []starriver.Depend{{ID: "b"}}},
+			{ID: "d", Name: "TestExecuted", Depends: []starriver.Depend{{ID: "a"}}},
+		},
+	}
+	re := NewRiverEngine()
+	defer re.Destroy()
+
+	t.Run("targets", func(t *testing.T) {
+		pipeline, err := NewPipeline(conf)
+		assert.NoError(t, err)
+		result := re.RunWithOptions(NewDataContext(context.Background(), pipeline, nil), pipeline, RunOptions{Targets: []string{"b"}})
+		assert.NoError(t, result.Error)
+		assert.Equal(t, starriver.PipelineStatusSuccess, result.Status)
+		assert.Equal(t, []string{"a", "b"}, executed.reset())
+		assert.Equal(t, map[string]starriver.TaskStatus{
+			"a": starriver.TaskStatusSuccess,
+			"b": starriver.TaskStatusSuccess,
+			"c": starriver.TaskStatusInit,
+			"d": starriver.TaskStatusInit,
+		}, result.State)
+	})
+
+	t.Run("from", func(t *testing.T) {
+		store := NewSharedDataStore()
+		pipeline, err := NewPipeline(conf)
+		assert.NoError(t, err)
+		result := re.Run(NewDataContext(context.Background(), pipeline, nil, SetSharedDataStore(store)), pipeline)
+		assert.Equal(t, starriver.PipelineStatusSuccess, result.Status)
+		assert.ElementsMatch(t, []string{"a", "b", "c", "d"}, executed.reset())
+
+		taskStatuses := make(map[string]starriver.TaskStatus)
+		for taskID, taskStatus := range result.State {
+			taskStatuses[taskID] = taskStatus
+		}
+		dataContext, pipeline, err := Rebuild(context.Background(), nil, conf, taskStatuses, store, nil)
+		assert.NoError(t, err)
+		result = re.RunWithOptions(dataContext, pipeline, RunOptions{From: []string{"b"}})
+		assert.NoError(t, result.Error)
+		assert.Equal(t, starriver.PipelineStatusSuccess, result.Status)
+		assert.Equal(t, []string{"b", "c"}, executed.reset())
+		for _, taskID := range []string{"a", "b", "c", "d"} {
+			assert.Equal(t, starriver.TaskStatusSuccess, result.State[taskID], taskID)
+		}
+		val, ok := store.GetDependNodeValue(context.Background(), "a", "out")
+		assert.True(t, ok)
+		assert.Equal(t, "a", val)
+	})
+
+	t.Run("targets and from", func(t *testing.T) {
+		pipeline, err := NewPipeline(conf)
+		assert.NoError(t, err)
+		result := re.RunWithOptions(NewDataContext(context.Background(), pipeline, nil), pipeline,
+			RunOptions{Targets: []string{"b"}, From: []string{"a"}})
+		assert.Equal(t, starriver.PipelineStatusSuccess, result.Status)
+		assert.Equal(t, []string{"a", "b"}, executed.reset())
+	})
+
+	t.Run("unknown task", func(t *testing.T) {
+		pipeline, err := NewPipeline(conf)
+		assert.NoError(t, err)
+		result := re.RunWithOptions(NewDataContext(context.Background(), pipeline, nil), pipeline, RunOptions{Targets: []string{"x"}})
+		assert.Equal(t, starriver.PipelineStatusFailure, result.Status)
+		assert.EqualError(t, result.Error, `task "x" not found in the pipeline "test_run_options"`)
+		assert.Empty(t, executed.reset())
+	})
+}
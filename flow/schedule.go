@@ -0,0 +1,281 @@
+package flow
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/robfig/cron/v3"
+	"github.com/sirupsen/logrus"
+
+	"github.com/thanksloving/starriver"
+)
+
+const (
+	// OverlapAllow runs the pipeline even if the previous run of the schedule is not done, it's the default policy
+	OverlapAllow OverlapPolicy = "allow"
+	// OverlapSkip skips the run if the previous run of the schedule is not done
+	OverlapSkip OverlapPolicy = "skip"
+	// OverlapQueue delays the run until the previous runs of the schedule are done
+	OverlapQueue OverlapPolicy = "queue"
+
+	// MisfireSkip drops the ticks missed while the schedule is paused or the previous run is not done, it's the default
+	MisfireSkip MisfirePolicy = "skip"
+	// MisfireFireOnce runs once for all the missed ticks, when the schedule is resumed or the previous run is done
+	MisfireFireOnce MisfirePolicy = "fire_once"
+)
+
+type (
+	ScheduleID int
+
+	OverlapPolicy string
+
+	MisfirePolicy string
+
+	ScheduleOption func(*schedule)
+
+	// ScheduleInfo is the state of a schedule, the fire times are zero if it never fires or the engine is not started
+	ScheduleInfo struct {
+		ID       ScheduleID    `json:"id"`
+		Spec     string        `json:"spec"`
+		Pipeline string        `json:"pipeline"`
+		Overlap  OverlapPolicy `json:"overlap"`
+		Misfire  MisfirePolicy `json:"misfire"`
+		Timezone string        `json:"timezone"`
+		Paused   bool          `json:"paused"`
+		Next     time.Time     `json:"next"`
+		Prev     time.Time     `json:"prev"`
+	}
+
+	schedule struct {
+		id       ScheduleID
+		entryID  cron.EntryID
+		spec     string
+		conf     starriver.PipelineConf
+		data     map[string]interface{}
+		overlap  OverlapPolicy
+		misfire  MisfirePolicy
+		missed   atomic.Bool // a tick is missed since the last run, only for MisfireFireOnce
+		location *time.Location
+		hook     func(id ScheduleID, result starriver.Result)
+		paused   atomic.Bool
+		running  sync.Mutex // held by the run unless the overlap policy is OverlapAllow
+		ctx      context.Context
+		cancel   context.CancelFunc // cancels the running runs when the schedule is removed
+	}
+)
+
+// the same as cron.WithSeconds, the seconds field is required
+var scheduleParser = cron.NewParser(cron.Second | cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow | cron.Descriptor)
+
+// WithOverlapPolicy sets how to deal with the run fired when the previous run is not done, default is OverlapAllow
+func WithOverlapPolicy(policy OverlapPolicy) ScheduleOption {
+	return func(s *schedule) {
+		s.overlap = policy
+	}
+}
+
+// WithMisfirePolicy sets how to deal with the ticks missed while the schedule is paused, or the previous run is not done
+// with OverlapSkip, default is MisfireSkip
+func WithMisfirePolicy(policy MisfirePolicy) ScheduleOption {
+	return func(s *schedule) {
+		s.misfire = policy
+	}
+}
+
+// WithTimezone sets the timezone of the spec, default is the local timezone or the CRON_TZ of the spec
+func WithTimezone(location *time.Location) ScheduleOption {
+	return func(s *schedule) {
+		s.location = location
+	}
+}
+
+// WithResultHook is called with the result of each run of the schedule
+func WithResultHook(hook func(id ScheduleID, result starriver.Result)) ScheduleOption {
+	return func(s *schedule) {
+		s.hook = hook
+	}
+}
+
+// Schedule runs the pipeline with the data on the cron spec with seconds, such as "0 30 * * * *" or "@every 1m".
+func (re *RiverEngine) Schedule(spec string, pipelineConf starriver.PipelineConf, data map[string]interface{},
+	options ...ScheduleOption) (ScheduleID, error) {
+	s := &schedule{spec: spec, conf: pipelineConf, data: data, overlap: OverlapAllow, misfire: MisfireSkip}
+	for _, option := range options {
+		option(s)
+	}
+	switch s.overlap {
+	case OverlapAllow, OverlapSkip, OverlapQueue:
+	default:
+		return 0, fmt.Errorf("unknown overlap policy %q", s.overlap)
+	}
+	switch s.misfire {
+	case MisfireSkip, MisfireFireOnce:
+	default:
+		return 0, fmt.Errorf("unknown misfire policy %q", s.misfire)
+	}
+	sched, err := scheduleParser.Parse(spec)
+	if err != nil {
+		return 0, err
+	}
+	if specSchedule, ok := sched.(*cron.SpecSchedule); ok {
+		if s.location != nil {
+			specSchedule.Location = s.location
+		} else {
+			s.location = specSchedule.Location
+		}
+	}
+	if errs := Validate(pipelineConf); len(errs) > 0 {
+		return 0, fmt.Errorf("invalid pipeline %q: %v", pipelineConf.Name, errs[0])
+	}
+	s.ctx, s.cancel = context.WithCancel(context.Background())
+	re.scheduleLock.Lock()
+	defer re.scheduleLock.Unlock()
+	// the id is read by the runs without the lock, so it's allocated before the job is registered
+	re.lastScheduleID++
+	s.id = re.lastScheduleID
+	s.entryID = re.cronClient.Schedule(sched, cron.FuncJob(func() { re.fire(s) }))
+	re.schedules[s.id] = s
+	re.cronClient.Start()
+	logrus.Infof("[Schedule] spec=%q, pipeline=%q, id=%d", spec, pipelineConf.Name, s.id)
+	return s.id, nil
+}
+
+// Unschedule removes the schedule and cancels the running runs of it
+func (re *RiverEngine) Unschedule(id ScheduleID) error {
+	re.scheduleLock.Lock()
+	defer re.scheduleLock.Unlock()
+	s, ok := re.schedules[id]
+	if !ok {
+		return fmt.Errorf("schedule %d not found", id)
+	}
+	re.removeSchedule(s)
+	return nil
+}
+
+// removeSchedule must be called with the schedule lock held
+func (re *RiverEngine) removeSchedule(s *schedule) {
+	re.cronClient.Remove(s.entryID)
+	delete(re.schedules, s.id)
+	s.cancel()
+}
+
+// PauseSchedule skips the runs of the schedule until it's resumed by ResumeSchedule
+func (re *RiverEngine) PauseSchedule(id ScheduleID) error {
+	s := re.getSchedule(id)
+	if s == nil {
+		return fmt.Errorf("schedule %d not found", id)
+	}
+	s.paused.Store(true)
+	return nil
+}
+
+// ResumeSchedule resumes the paused schedule, the missed ticks are fired once if the misfire policy is MisfireFireOnce
+func (re *RiverEngine) ResumeSchedule(id ScheduleID) error {
+	s := re.getSchedule(id)
+	if s == nil {
+		return fmt.Errorf("schedule %d not found", id)
+	}
+	if s.paused.Swap(false) && s.missed.Swap(false) {
+		go re.fire(s)
+	}
+	return nil
+}
+
+// ListSchedules returns the schedules sorted by the id
+func (re *RiverEngine) ListSchedules() []ScheduleInfo {
+	re.scheduleLock.RLock()
+	defer re.scheduleLock.RUnlock()
+	entries := make(map[cron.EntryID]cron.Entry, len(re.schedules))
+	for _, entry := range re.cronClient.Entries() {
+		entries[entry.ID] = entry
+	}
+	infos := make([]ScheduleInfo, 0, len(re.schedules))
+	for id, s := range re.schedules {
+		info := ScheduleInfo{
+			ID:       id,
+			Spec:     s.spec,
+			Pipeline: s.conf.Name,
+			Overlap:  s.overlap,
+			Misfire:  s.misfire,
+			Paused:   s.paused.Load(),
+			Next:     entries[s.entryID].Next,
+			Prev:     entries[s.entryID].Prev,
+		}
+		if s.location != nil {
+			info.Timezone = s.location.String()
+		}
+		infos = append(infos, info)
+	}
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].ID < infos[j].ID
+	})
+	return infos
+}
+
+func (re *RiverEngine) getSchedule(id ScheduleID) *schedule {
+	re.scheduleLock.RLock()
+	defer re.scheduleLock.RUnlock()
+	return re.schedules[id]
+}
+
+// fire runs the pipeline of the schedule once, following the overlap and misfire policies
+func (re *RiverEngine) fire(s *schedule) {
+	if s.paused.Load() {
+		s.miss()
+		return
+	}
+	switch s.overlap {
+	case OverlapSkip:
+		for s.running.TryLock() {
+			re.runSchedule(s)
+			s.running.Unlock()
+			// the ticks missed during the run are fired once after it
+			if !s.missed.Swap(false) {
+				return
+			}
+		}
+		logrus.Infof("[Schedule] skip the run of schedule %d since the previous run is not done", s.id)
+		s.miss()
+		return
+	case OverlapQueue:
+		s.running.Lock()
+		defer s.running.Unlock()
+	}
+	re.runSchedule(s)
+}
+
+func (re *RiverEngine) runSchedule(s *schedule) {
+	var result starriver.Result
+	pipeline, err := NewPipeline(s.conf)
+	if err != nil {
+		logrus.Errorf("[Schedule] NewPipeline error %v", err)
+		result = starriver.Result{Status: starriver.PipelineStatusFailure, Error: err}
+	} else {
+		dataContext := NewDataContext(s.ctx, pipeline, s.data)
+		result = re.Run(dataContext, pipeline)
+	}
+	if s.hook != nil {
+		s.hook(s.id, result)
+	}
+}
+
+// miss remembers the missed tick to fire it later if the misfire policy is MisfireFireOnce
+func (s *schedule) miss() {
+	if s.misfire == MisfireFireOnce {
+		s.missed.Store(true)
+	}
+}
+
+// CronRun runs the pipeline on the cron spec
+//
+// Deprecated: use Schedule, which reports the error and the schedule can be managed.
+func (re *RiverEngine) CronRun(spec string, pipelineConf starriver.PipelineConf, data map[string]interface{}) {
+	id, err := re.Schedule(spec, pipelineConf, data, WithResultHook(func(id ScheduleID, result starriver.Result) {
+		logrus.Infof("[Cron]spec=%q, pipeline=%q, data=%+v, result=%+v", spec, pipelineConf.Name, data, result)
+	}))
+	logrus.Infof("[Cron]AddFunc, spec=%q, pipeline=%q, entryID=%d, err=%v", spec, pipelineConf.Name, id, err)
+}
@@ -0,0 +1,189 @@
+package flow
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/thanksloving/starriver"
+)
+
+func waitPipeline(waitingTime string) starriver.PipelineConf {
+	return starriver.PipelineConf{
+		Name: "test_schedule",
+		Pipeline: []starriver.Task{
+			{
+				ID:   "wait",
+				Name: "Wait",
+				Config: starriver.TaskConfigure{
+					Params: []starriver.Param{{Name: "WaitingTime", Type: starriver.ParamTypeLiteral, Literal: waitingTime}},
+				},
+			},
+		},
+	}
+}
+
+func TestSchedule(t *testing.T) {
+	re := NewRiverEngine()
+	defer re.Destroy()
+
+	_, err := re.Schedule("invalid spec", waitPipeline("1ms"), nil)
+	assert.Error(t, err)
+	_, err = re.Schedule("* * * * * *", starriver.PipelineConf{Name: "invalid", Pipeline: []starriver.Task{{ID: "x", Name: "NotExist"}}}, nil)
+	assert.Error(t, err)
+	_, err = re.Schedule("* * * * * *", waitPipeline("1ms"), nil, WithOverlapPolicy("unknown"))
+	assert.Error(t, err)
+
+	results := make(chan starriver.Result, 10)
+	id, err := re.Schedule("* * * * * *", waitPipeline("1ms"), nil, WithResultHook(func(id ScheduleID, result starriver.Result) {
+		results <- result
+	}))
+	assert.NoError(t, err)
+	select {
+	case result := <-results:
+		assert.Equal(t, starriver.PipelineStatusSuccess, result.Status)
+	case <-time.After(3 * time.Second):
+		assert.Fail(t, "the schedule is not fired")
+	}
+
+	zone := time.FixedZone("UTC+8", 8*3600)
+	dailyID, err := re.Schedule("0 0 8 * * *", waitPipeline("1ms"), nil, WithTimezone(zone), WithOverlapPolicy(OverlapSkip))
+	assert.NoError(t, err)
+
+	assert.NoError(t, re.PauseSchedule(dailyID))
+	schedules := re.ListSchedules()
+	assert.Len(t, schedules, 2)
+	assert.Equal(t, id, schedules[0].ID)
+	assert.False(t, schedules[0].Prev.IsZero())
+	assert.Equal(t, OverlapAllow, schedules[0].Overlap)
+	assert.Equal(t, dailyID, schedules[1].ID)
+	assert.True(t, schedules[1].Paused)
+	assert.Equal(t, OverlapSkip, schedules[1].Overlap)
+	assert.Equal(t, "UTC+8", schedules[1].Timezone)
+	assert.Equal(t, 8, schedules[1].Next.In(zone).Hour())
+	assert.True(t, schedules[1].Prev.IsZero())
+
+	assert.NoError(t, re.ResumeSchedule(dailyID))
+	assert.False(t, re.ListSchedules()[1].Paused)
+
+	assert.NoError(t, re.Unschedule(id))
+	assert.Error(t, re.Unschedule(id))
+	assert.Error(t, re.PauseSchedule(id))
+	assert.Error(t, re.ResumeSchedule(id))
+	assert.Len(t, re.ListSchedules(), 1)
+}
+
+func TestSchedule_Overlap(t *testing.T) {
+	re := NewRiverEngine()
+	defer re.Destroy()
+
+	for policy, expected := range map[OverlapPolicy]int32{OverlapAllow: 3, OverlapSkip: 1, OverlapQueue: 3} {
+		var runs int32
+		// never fired by the cron, the runs are fired by hand
+		id, err := re.Schedule("0 0 0 1 1 *", waitPipeline("100ms"), nil, WithOverlapPolicy(policy),
+			WithResultHook(func(id ScheduleID, result starriver.Result) {
+				atomic.AddInt32(&runs, 1)
+			}))
+		assert.NoError(t, err)
+		s := re.getSchedule(id)
+
+		var wg sync.WaitGroup
+		start := time.Now()
+		for i := 0; i < 3; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				re.fire(s)
+			}()
+		}
+		wg.Wait()
+		assert.Equal(t, expected, runs, policy)
+		if policy == OverlapQueue {
+			// the runs are one by one
+			assert.GreaterOrEqual(t, time.Since(start), 300*time.Millisecond)
+		}
+
+		// the paused schedule is not run
+		assert.NoError(t, re.PauseSchedule(id))
+		re.fire(s)
+		assert.Equal(t, expected, runs, policy)
+		assert.NoError(t, re.Unschedule(id))
+	}
+}
+
+func TestSchedule_Cancel(t *testing.T) {
+	re := NewRiverEngine()
+	defer re.Destroy()
+
+	for _, remove := range map[string]func(id ScheduleID){
+		"unschedule": func(id ScheduleID) { assert.NoError(t, re.Unschedule(id)) },
+		"destroy":    func(ScheduleID) { re.Destroy() },
+	} {
+		results := make(chan starriver.Result, 1)
+		// never fired by the cron, the run is fired by hand
+		id, err := re.Schedule("0 0 0 1 1 *", waitPipeline("1s"), nil, WithResultHook(func(id ScheduleID, result starriver.Result) {
+			results <- result
+		}))
+		assert.NoError(t, err)
+		go re.fire(re.getSchedule(id))
+		// wait until the component is executing
+		time.Sleep(100 * time.Millisecond)
+
+		start := time.Now()
+		remove(id)
+		select {
+		case result := <-results:
+			assert.NotEqual(t, starriver.PipelineStatusSuccess, result.Status)
+			assert.Less(t, time.Since(start), 500*time.Millisecond)
+		case <-time.After(time.Second):
+			assert.Fail(t, "the run is not cancelled")
+		}
+		assert.Empty(t, re.ListSchedules())
+	}
+}
+
+func TestSchedule_Misfire(t *testing.T) {
+	re := NewRiverEngine()
+	defer re.Destroy()
+
+	_, err := re.Schedule("* * * * * *", waitPipeline("1ms"), nil, WithMisfirePolicy("unknown"))
+	assert.Error(t, err)
+
+	for policy, expected := range map[MisfirePolicy]int32{MisfireSkip: 0, MisfireFireOnce: 1} {
+		var runs int32
+		// never fired by the cron, the runs are fired by hand
+		id, err := re.Schedule("0 0 0 1 1 *", waitPipeline("100ms"), nil, WithOverlapPolicy(OverlapSkip), WithMisfirePolicy(policy),
+			WithResultHook(func(id ScheduleID, result starriver.Result) {
+				atomic.AddInt32(&runs, 1)
+			}))
+		assert.NoError(t, err)
+		s := re.getSchedule(id)
+		assert.Equal(t, policy, re.ListSchedules()[0].Misfire)
+
+		// the ticks missed while paused
+		assert.NoError(t, re.PauseSchedule(id))
+		re.fire(s)
+		re.fire(s)
+		assert.NoError(t, re.ResumeSchedule(id))
+		assert.Eventually(t, func() bool { return atomic.LoadInt32(&runs) == expected }, time.Second, 10*time.Millisecond, policy)
+		time.Sleep(150 * time.Millisecond)
+		assert.Equal(t, expected, atomic.LoadInt32(&runs), policy)
+
+		// the ticks missed while the previous run is not done
+		atomic.StoreInt32(&runs, 0)
+		var wg sync.WaitGroup
+		for i := 0; i < 3; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				re.fire(s)
+			}()
+		}
+		wg.Wait()
+		assert.Equal(t, 1+expected, atomic.LoadInt32(&runs), policy)
+		assert.NoError(t, re.Unschedule(id))
+	}
+}
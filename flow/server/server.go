@@ -0,0 +1,404 @@
+// Package server exposes a RiverEngine over HTTP:
+//
+//	POST /pipelines/{name}/runs     run the pipeline, {"request_id": "", "data": {}, "async": false}
+//	GET  /runs/{requestID}          status, state and result data of the run
+//	POST /runs/{requestID}/cancel   cancel the running run
+//	POST /runs/{requestID}/resume   resume the blocked run, {"data": {}}
+//	GET  /components                metadata of the registered components
+//
+// The request id of a run is prefixed by the pipeline name such as "demo#1", the "#" must be escaped as "%23" in the path.
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/thanksloving/starriver"
+	"github.com/thanksloving/starriver/flow"
+)
+
+const (
+	// RunStatusRunning is the status of a run which is not done yet
+	RunStatusRunning = "running"
+
+	defaultMaxFinishedRuns = 1000
+)
+
+type (
+	Option func(*Server)
+
+	Server struct {
+		engine      *flow.RiverEngine
+		lock        sync.RWMutex
+		pipelines   map[string]starriver.PipelineConf
+		runs        map[string]*run
+		finished    []*run // the finished runs kept in runs, the oldest first
+		maxFinished int
+	}
+
+	run struct {
+		lock      sync.RWMutex
+		requestID string
+		pipeline  string
+		result    *starriver.Result // nil when it's running
+		cancel    context.CancelFunc
+	}
+
+	RunRequest struct {
+		RequestID string                 `json:"request_id"`
+		Data      map[string]interface{} `json:"data"`
+		Async     bool                   `json:"async"` // return at once without waiting for the result
+	}
+
+	ResumeRequest struct {
+		Data map[string]interface{} `json:"data"` // put into the shared data before resuming
+	}
+
+	RunResponse struct {
+		RequestID string                          `json:"request_id"`
+		Pipeline  string                          `json:"pipeline,omitempty"`
+		Status    string                          `json:"status"`
+		State     map[string]starriver.TaskStatus `json:"state,omitempty"`
+		Skipped   []string                        `json:"skipped,omitempty"`
+		Data      map[string]interface{}          `json:"data,omitempty"`
+		Error     string                          `json:"error,omitempty"`
+		Report    *starriver.Report               `json:"report,omitempty"`
+
+		Compensations []starriver.CompensationRecord `json:"compensations,omitempty"`
+		Hooks         []starriver.TaskRecord         `json:"hooks,omitempty"`
+	}
+
+	ErrorResponse struct {
+		Error string `json:"error"`
+	}
+)
+
+var _ http.Handler = (*Server)(nil)
+
+// WithPipelines makes the pipelines runnable by their names
+func WithPipelines(confs ...starriver.PipelineConf) Option {
+	return func(s *Server) {
+		for _, conf := range confs {
+			s.pipelines[conf.Name] = conf
+		}
+	}
+}
+
+// WithMaxFinishedRuns keeps at most n finished runs in memory, default is 1000. The evicted runs can still be got
+// from the journal of the engine, without the result data.
+func WithMaxFinishedRuns(n int) Option {
+	return func(s *Server) {
+		if n > 0 {
+			s.maxFinished = n
+		}
+	}
+}
+
+// New returns the handler of the engine. Resume requires the journal of the engine, set it by flow.SetJournal,
+// e.g. flow.NewMemoryJournal(); without the journal the blocked runs can not be resumed.
+func New(engine *flow.RiverEngine, options ...Option) *Server {
+	s := &Server{
+		engine:      engine,
+		pipelines:   make(map[string]starriver.PipelineConf),
+		runs:        make(map[string]*run),
+		maxFinished: defaultMaxFinishedRuns,
+	}
+	for _, option := range options {
+		option(s)
+	}
+	return s
+}
+
+// AddPipeline validates the pipeline and makes it runnable by its name, the pipeline with the same name is replaced.
+func (s *Server) AddPipeline(conf starriver.PipelineConf) error {
+	if errs := flow.Validate(conf); len(errs) > 0 {
+		msgs := make([]string, len(errs))
+		for idx, err := range errs {
+			msgs[idx] = err.Error()
+		}
+		return fmt.Errorf("invalid pipeline %q: %s", conf.Name, strings.Join(msgs, "; "))
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.pipelines[conf.Name] = conf
+	return nil
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	switch {
+	case len(parts) == 3 && parts[0] == "pipelines" && parts[2] == "runs":
+		if allowMethod(w, r, http.MethodPost) {
+			s.handleRun(w, r, parts[1])
+		}
+	case len(parts) == 2 && parts[0] == "runs":
+		if allowMethod(w, r, http.MethodGet) {
+			s.handleGetRun(w, parts[1])
+		}
+	case len(parts) == 3 && parts[0] == "runs" && parts[2] == "cancel":
+		if allowMethod(w, r, http.MethodPost) {
+			s.handleCancel(w, parts[1])
+		}
+	case len(parts) == 3 && parts[0] == "runs" && parts[2] == "resume":
+		if allowMethod(w, r, http.MethodPost) {
+			s.handleResume(w, r, parts[1])
+		}
+	case len(parts) == 1 && parts[0] == "components":
+		if allowMethod(w, r, http.MethodGet) {
+			writeJSON(w, http.StatusOK, flow.DescribeComponents())
+		}
+	default:
+		writeError(w, http.StatusNotFound, fmt.Errorf("%s not found", r.URL.Path))
+	}
+}
+
+func (s *Server) handleRun(w http.ResponseWriter, r *http.Request, name string) {
+	var req RunRequest
+	if err := decodeBody(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	s.lock.RLock()
+	conf, ok := s.pipelines[name]
+	s.lock.RUnlock()
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Errorf("pipeline %q not found", name))
+		return
+	}
+	pipeline, err := flow.NewPipeline(conf)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	parent := r.Context()
+	if req.Async {
+		parent = context.Background()
+	}
+	ctx, cancel := context.WithCancel(parent)
+	var dataContext starriver.DataContext
+	if req.RequestID != "" {
+		dataContext = flow.NewDataContext(ctx, pipeline, req.Data, flow.SetRequestID(req.RequestID))
+	} else {
+		dataContext = flow.NewDataContext(ctx, pipeline, req.Data)
+	}
+	rn := &run{requestID: dataContext.GetRequestID(), pipeline: name, cancel: cancel}
+	if err = s.register(rn); err != nil {
+		cancel()
+		dataContext.Release()
+		writeError(w, http.StatusConflict, err)
+		return
+	}
+	execute := func() {
+		defer cancel()
+		result := s.engine.Run(dataContext, pipeline)
+		s.finish(rn, result)
+	}
+	if req.Async {
+		go execute()
+		writeJSON(w, http.StatusAccepted, rn.view())
+		return
+	}
+	execute()
+	writeJSON(w, http.StatusOK, rn.view())
+}
+
+func (s *Server) handleGetRun(w http.ResponseWriter, requestID string) {
+	if rn := s.getRun(requestID); rn != nil {
+		writeJSON(w, http.StatusOK, rn.view())
+		return
+	}
+	if s.engine.Journal == nil {
+		writeError(w, http.StatusNotFound, fmt.Errorf("run %q not found", requestID))
+		return
+	}
+	record, err := s.engine.Journal.Get(requestID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if record == nil {
+		writeError(w, http.StatusNotFound, fmt.Errorf("run %q not found", requestID))
+		return
+	}
+	status := string(record.Status)
+	if !record.Finished {
+		status = RunStatusRunning
+	}
+	writeJSON(w, http.StatusOK, RunResponse{
+		RequestID: record.RequestID,
+		Pipeline:  record.Conf.Name,
+		Status:    status,
+		State:     record.State,
+	})
+}
+
+func (s *Server) handleCancel(w http.ResponseWriter, requestID string) {
+	rn := s.getRun(requestID)
+	if rn == nil {
+		writeError(w, http.StatusNotFound, fmt.Errorf("run %q not found", requestID))
+		return
+	}
+	if !rn.stop() {
+		writeError(w, http.StatusConflict, fmt.Errorf("run %q is done", requestID))
+		return
+	}
+	writeJSON(w, http.StatusAccepted, rn.view())
+}
+
+func (s *Server) handleResume(w http.ResponseWriter, r *http.Request, requestID string) {
+	var req ResumeRequest
+	if err := decodeBody(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if s.engine.Journal == nil {
+		writeError(w, http.StatusNotImplemented, errors.New("resume requires the journal of the engine"))
+		return
+	}
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+	rn := &run{requestID: requestID, cancel: cancel}
+	prev := s.getRun(requestID)
+	if prev != nil {
+		rn.pipeline = prev.pipeline
+	}
+	if err := s.register(rn); err != nil {
+		writeError(w, http.StatusConflict, err)
+		return
+	}
+	result, err := s.engine.Resume(ctx, requestID, req.Data)
+	if err != nil {
+		// the run is not resumed, restore the previous record
+		s.restore(rn, prev)
+		writeError(w, http.StatusConflict, err)
+		return
+	}
+	s.finish(rn, result)
+	writeJSON(w, http.StatusOK, rn.view())
+}
+
+// register records the run, it fails if the run with the same request id is running
+func (s *Server) register(rn *run) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if prev, ok := s.runs[rn.requestID]; ok && prev.running() {
+		return fmt.Errorf("run %q is running", rn.requestID)
+	}
+	s.runs[rn.requestID] = rn
+	return nil
+}
+
+// restore replaces the run with the previous one if it's not replaced by others
+func (s *Server) restore(rn, prev *run) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.runs[rn.requestID] != rn {
+		return
+	}
+	if prev != nil {
+		s.runs[rn.requestID] = prev
+	} else {
+		delete(s.runs, rn.requestID)
+	}
+}
+
+// finish records the result of the run, and evicts the oldest finished runs if there are more than maxFinished
+func (s *Server) finish(rn *run, result starriver.Result) {
+	rn.finish(result)
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.finished = append(s.finished, rn)
+	for len(s.finished) > s.maxFinished {
+		oldest := s.finished[0]
+		s.finished[0] = nil
+		s.finished = s.finished[1:]
+		// the run may be replaced by a resumed one
+		if s.runs[oldest.requestID] == oldest {
+			delete(s.runs, oldest.requestID)
+		}
+	}
+}
+
+func (s *Server) getRun(requestID string) *run {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	return s.runs[requestID]
+}
+
+func (rn *run) running() bool {
+	rn.lock.RLock()
+	defer rn.lock.RUnlock()
+	return rn.result == nil
+}
+
+func (rn *run) finish(result starriver.Result) {
+	rn.lock.Lock()
+	defer rn.lock.Unlock()
+	rn.result = &result
+}
+
+// stop cancels the run, it returns false if the run is done
+func (rn *run) stop() bool {
+	rn.lock.RLock()
+	defer rn.lock.RUnlock()
+	if rn.result != nil {
+		return false
+	}
+	rn.cancel()
+	return true
+}
+
+func (rn *run) view() RunResponse {
+	rn.lock.RLock()
+	defer rn.lock.RUnlock()
+	resp := RunResponse{RequestID: rn.requestID, Pipeline: rn.pipeline, Status: RunStatusRunning}
+	if rn.result != nil {
+		resp.Status = string(rn.result.Status)
+		resp.State = rn.result.State
+		resp.Skipped = rn.result.Skipped
+		resp.Data = rn.result.Data
+		resp.Report = rn.result.Report
+		resp.Compensations = rn.result.Compensations
+		resp.Hooks = rn.result.Hooks
+		if rn.result.Error != nil {
+			resp.Error = rn.result.Error.Error()
+		}
+	}
+	return resp
+}
+
+func allowMethod(w http.ResponseWriter, r *http.Request, method string) bool {
+	if r.Method == method {
+		return true
+	}
+	w.Header().Set("Allow", method)
+	writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method %s not allowed", r.Method))
+	return false
+}
+
+// decodeBody decodes the JSON body into v, an empty body is allowed
+func decodeBody(r *http.Request, v interface{}) error {
+	if r.Body == nil {
+		return nil
+	}
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("invalid request body: %v", err)
+	}
+	return nil
+}
+
+func writeJSON(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, code int, err error) {
+	writeJSON(w, code, ErrorResponse{Error: err.Error()})
+}
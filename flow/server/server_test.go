@@ -0,0 +1,228 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/thanksloving/starriver"
+	"github.com/thanksloving/starriver/flow"
+)
+
+func testServer(t *testing.T, options ...Option) *httptest.Server {
+	s := New(flow.NewRiverEngine(flow.SetJournal(flow.NewMemoryJournal())), options...)
+	assert.NoError(t, s.AddPipeline(starriver.PipelineConf{
+		Name:   "hello",
+		Result: []string{"out"},
+		Pipeline: []starriver.Task{
+			{
+				ID:   "task1",
+				Name: "Template",
+				Config: starriver.TaskConfigure{
+					Params: []starriver.Param{
+						{Name: "Template", Type: starriver.ParamTypeLiteral, Literal: `hello {{ str "name" }}`},
+						{Name: "OutputKey", Type: starriver.ParamTypeLiteral, Literal: "out"},
+						{Name: "Shared", Type: starriver.ParamTypeLiteral, Literal: true},
+					},
+				},
+			},
+		},
+	}))
+	assert.NoError(t, s.AddPipeline(starriver.PipelineConf{
+		Name: "slow",
+		Pipeline: []starriver.Task{
+			{
+				ID:   "wait",
+				Name: "Wait",
+				Config: starriver.TaskConfigure{
+					Params: []starriver.Param{{Name: "WaitingTime", Type: starriver.ParamTypeLiteral, Literal: "10s"}},
+				},
+			},
+		},
+	}))
+	assert.NoError(t, s.AddPipeline(starriver.PipelineConf{
+		Name:   "approval",
+		Result: []string{"out"},
+		Pipeline: []starriver.Task{
+			{
+				ID:   "approve",
+				Name: "WaitForSignal",
+				Config: starriver.TaskConfigure{
+					Params: []starriver.Param{{Name: "Signal", Type: starriver.ParamTypeLiteral, Literal: "approval"}},
+				},
+			},
+			{
+				ID:   "notify",
+				Name: "Template",
+				Config: starriver.TaskConfigure{
+					Params: []starriver.Param{
+						{Name: "Template", Type: starriver.ParamTypeLiteral, Literal: `{{ str "signal.approval" }}`},
+						{Name: "OutputKey", Type: starriver.ParamTypeLiteral, Literal: "out"},
+						{Name: "Shared", Type: starriver.ParamTypeLiteral, Literal: true},
+					},
+				},
+				Depends: []starriver.Depend{{ID: "approve"}},
+			},
+		},
+	}))
+	assert.Error(t, s.AddPipeline(starriver.PipelineConf{Name: "invalid", Pipeline: []starriver.Task{{ID: "x", Name: "NotExist"}}}))
+	ts := httptest.NewServer(s)
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+func call(t *testing.T, method, u string, body interface{}, v interface{}) int {
+	var reader io.Reader = http.NoBody
+	if body != nil {
+		bs, err := json.Marshal(body)
+		assert.NoError(t, err)
+		reader = bytes.NewReader(bs)
+	}
+	req, err := http.NewRequest(method, u, reader)
+	assert.NoError(t, err)
+	resp, err := http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+	if v != nil {
+		assert.NoError(t, json.NewDecoder(resp.Body).Decode(v))
+	}
+	return resp.StatusCode
+}
+
+func runURL(ts *httptest.Server, requestID string, action ...string) string {
+	u := ts.URL + "/runs/" + url.PathEscape(requestID)
+	for _, a := range action {
+		u += "/" + a
+	}
+	return u
+}
+
+func TestServer_Run(t *testing.T) {
+	ts := testServer(t)
+
+	var resp RunResponse
+	code := call(t, http.MethodPost, ts.URL+"/pipelines/hello/runs", RunRequest{RequestID: "1", Data: map[string]interface{}{"name": "jimmy"}}, &resp)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "hello#1", resp.RequestID)
+	assert.Equal(t, string(starriver.PipelineStatusSuccess), resp.Status)
+	assert.Equal(t, "hello jimmy", resp.Data["out"])
+	assert.Equal(t, starriver.TaskStatusSuccess, resp.State["task1"])
+
+	resp = RunResponse{}
+	assert.Equal(t, http.StatusOK, call(t, http.MethodGet, runURL(ts, "hello#1"), nil, &resp))
+	assert.Equal(t, "hello jimmy", resp.Data["out"])
+
+	// async
+	resp = RunResponse{}
+	code = call(t, http.MethodPost, ts.URL+"/pipelines/hello/runs", RunRequest{RequestID: "2", Data: map[string]interface{}{"name": "tom"}, Async: true}, &resp)
+	assert.Equal(t, http.StatusAccepted, code)
+	assert.Equal(t, "hello#2", resp.RequestID)
+	assert.Eventually(t, func() bool {
+		resp = RunResponse{}
+		call(t, http.MethodGet, runURL(ts, "hello#2"), nil, &resp)
+		return resp.Status == string(starriver.PipelineStatusSuccess)
+	}, time.Second, 10*time.Millisecond)
+	assert.Equal(t, "hello tom", resp.Data["out"])
+
+	var errResp ErrorResponse
+	assert.Equal(t, http.StatusNotFound, call(t, http.MethodPost, ts.URL+"/pipelines/unknown/runs", nil, &errResp))
+	assert.NotEmpty(t, errResp.Error)
+	assert.Equal(t, http.StatusNotFound, call(t, http.MethodGet, runURL(ts, "hello#3"), nil, nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, call(t, http.MethodGet, ts.URL+"/pipelines/hello/runs", nil, nil))
+	assert.Equal(t, http.StatusNotFound, call(t, http.MethodGet, ts.URL+"/unknown", nil, nil))
+}
+
+func TestServer_Cancel(t *testing.T) {
+	ts := testServer(t)
+
+	var resp RunResponse
+	code := call(t, http.MethodPost, ts.URL+"/pipelines/slow/runs", RunRequest{RequestID: "1", Async: true}, &resp)
+	assert.Equal(t, http.StatusAccepted, code)
+	assert.Equal(t, RunStatusRunning, resp.Status)
+
+	// the same request id can not run twice at the same time
+	assert.Equal(t, http.StatusConflict, call(t, http.MethodPost, ts.URL+"/pipelines/slow/runs", RunRequest{RequestID: "1", Async: true}, nil))
+
+	assert.Equal(t, http.StatusAccepted, call(t, http.MethodPost, runURL(ts, "slow#1", "cancel"), nil, nil))
+	assert.Eventually(t, func() bool {
+		resp = RunResponse{}
+		call(t, http.MethodGet, runURL(ts, "slow#1"), nil, &resp)
+		return resp.Status == string(starriver.PipelineStatusFailure)
+	}, time.Second, 10*time.Millisecond)
+	assert.Equal(t, http.StatusConflict, call(t, http.MethodPost, runURL(ts, "slow#1", "cancel"), nil, nil))
+	assert.Equal(t, http.StatusNotFound, call(t, http.MethodPost, runURL(ts, "slow#2", "cancel"), nil, nil))
+}
+
+func TestServer_Resume(t *testing.T) {
+	ts := testServer(t)
+
+	var resp RunResponse
+	call(t, http.MethodPost, ts.URL+"/pipelines/approval/runs", RunRequest{RequestID: "1"}, &resp)
+	assert.Equal(t, string(starriver.PipelineStatusBlocked), resp.Status)
+
+	resp = RunResponse{}
+	code := call(t, http.MethodPost, runURL(ts, "approval#1", "resume"), ResumeRequest{Data: map[string]interface{}{starriver.SignalKey("approval"): "approved"}}, &resp)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, string(starriver.PipelineStatusSuccess), resp.Status)
+	assert.Equal(t, "approved", resp.Data["out"])
+
+	// not blocked any more
+	assert.Equal(t, http.StatusConflict, call(t, http.MethodPost, runURL(ts, "approval#1", "resume"), nil, nil))
+	resp = RunResponse{}
+	call(t, http.MethodGet, runURL(ts, "approval#1"), nil, &resp)
+	assert.Equal(t, string(starriver.PipelineStatusSuccess), resp.Status)
+}
+
+func TestServer_Components(t *testing.T) {
+	ts := testServer(t)
+
+	var components []flow.ComponentInfo
+	assert.Equal(t, http.StatusOK, call(t, http.MethodGet, ts.URL+"/components", nil, &components))
+	names := make(map[string]flow.ComponentInfo)
+	for _, component := range components {
+		names[component.Name] = component
+	}
+	assert.Contains(t, names, "Template")
+	assert.Contains(t, names, "WaitForSignal")
+	assert.Equal(t, "Signal", names["WaitForSignal"].Input[0].Key)
+	assert.True(t, names["WaitForSignal"].Input[0].Required)
+}
+
+func TestServer_WithoutJournal(t *testing.T) {
+	engine := flow.NewRiverEngine()
+	s := New(engine)
+	assert.Nil(t, engine.Journal)
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	var errResp ErrorResponse
+	assert.Equal(t, http.StatusNotImplemented, call(t, http.MethodPost, runURL(ts, "approval#1", "resume"), nil, &errResp))
+	assert.NotEmpty(t, errResp.Error)
+	assert.Equal(t, http.StatusNotFound, call(t, http.MethodGet, runURL(ts, "approval#1"), nil, nil))
+}
+
+func TestServer_MaxFinishedRuns(t *testing.T) {
+	ts := testServer(t, WithMaxFinishedRuns(1))
+
+	for _, requestID := range []string{"1", "2"} {
+		code := call(t, http.MethodPost, ts.URL+"/pipelines/hello/runs", RunRequest{RequestID: requestID, Data: map[string]interface{}{"name": "jimmy"}}, nil)
+		assert.Equal(t, http.StatusOK, code)
+	}
+
+	// the evicted run is got from the journal without the data
+	var resp RunResponse
+	assert.Equal(t, http.StatusOK, call(t, http.MethodGet, runURL(ts, "hello#1"), nil, &resp))
+	assert.Equal(t, string(starriver.PipelineStatusSuccess), resp.Status)
+	assert.Empty(t, resp.Data)
+
+	resp = RunResponse{}
+	assert.Equal(t, http.StatusOK, call(t, http.MethodGet, runURL(ts, "hello#2"), nil, &resp))
+	assert.Equal(t, "hello jimmy", resp.Data["out"])
+}
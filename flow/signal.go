@@ -0,0 +1,19 @@
+package flow
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/thanksloving/starriver"
+)
+
+// Signal sends the signal to the blocked run, the payload is stored in the shared data with starriver.SignalKey(signalName),
+// and then the run is resumed, the WaitForSignal tasks waiting for the signal will pass.
+// The run is restored from the journal, so the engine must be created with SetJournal. Without the journal, rebuild the
+// run by Rebuild with the payload in the initial data instead.
+func (re *RiverEngine) Signal(requestID, signalName string, payload interface{}) (starriver.Result, error) {
+	if re.Journal == nil {
+		return starriver.Result{}, fmt.Errorf("signal %q of run %q needs the journal, set it by SetJournal", signalName, requestID)
+	}
+	return re.Resume(context.Background(), requestID, map[string]interface{}{starriver.SignalKey(signalName): payload})
+}
@@ -0,0 +1,93 @@
+package flow
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/thanksloving/starriver"
+)
+
+func TestSignal(t *testing.T) {
+	conf := starriver.PipelineConf{
+		Name:   "test_signal",
+		Result: []string{"out"},
+		Pipeline: []starriver.Task{
+			{
+				ID:   "approve",
+				Name: "WaitForSignal",
+				Config: starriver.TaskConfigure{
+					Params: []starriver.Param{{Name: "Signal", Type: starriver.ParamTypeLiteral, Literal: "approval"}},
+				},
+			},
+			{
+				ID:   "notify",
+				Name: "Template",
+				Config: starriver.TaskConfigure{
+					Params: []starriver.Param{
+						{Name: "Template", Type: starriver.ParamTypeLiteral, Literal: `{{ str "name" }} {{ str "signal.approval" }}`},
+						{Name: "OutputKey", Type: starriver.ParamTypeLiteral, Literal: "out"},
+						{Name: "Shared", Type: starriver.ParamTypeLiteral, Literal: true},
+					},
+				},
+				Depends: []starriver.Depend{{ID: "approve"}},
+			},
+		},
+	}
+	re := NewRiverEngine(SetJournal(NewMemoryJournal()))
+	defer re.Destroy()
+
+	_, err := re.Signal("test_signal#1", "approval", "approved")
+	assert.Error(t, err)
+
+	pipeline, err := NewPipeline(conf)
+	assert.NoError(t, err)
+	dc := NewDataContext(context.Background(), pipeline, map[string]interface{}{"name": "jimmy"}, SetRequestID("1"))
+	result := re.Run(dc, pipeline)
+	assert.Equal(t, starriver.PipelineStatusBlocked, result.Status)
+	assert.Equal(t, starriver.TaskStatusBlocked, result.State["approve"])
+
+	result, err = re.Signal("test_signal#1", "approval", "approved")
+	assert.NoError(t, err)
+	assert.Equal(t, starriver.PipelineStatusSuccess, result.Status)
+	assert.Equal(t, "jimmy approved", result.Data["out"])
+
+	// the run is not blocked any more
+	_, err = re.Signal("test_signal#1", "approval", "approved")
+	assert.Error(t, err)
+}
+
+func TestSignal_WithoutJournal(t *testing.T) {
+	conf := starriver.PipelineConf{
+		Name: "test_signal_without_journal",
+		Pipeline: []starriver.Task{
+			{
+				ID:   "approve",
+				Name: "WaitForSignal",
+				Config: starriver.TaskConfigure{
+					Params: []starriver.Param{{Name: "Signal", Type: starriver.ParamTypeLiteral, Literal: "approval"}},
+				},
+			},
+		},
+	}
+	re := NewRiverEngine()
+	defer re.Destroy()
+
+	store := NewSharedDataStore()
+	pipeline, err := NewPipeline(conf)
+	assert.NoError(t, err)
+	result := re.Run(NewDataContext(context.Background(), pipeline, nil, SetRequestID("1"), SetSharedDataStore(store)), pipeline)
+	assert.Equal(t, starriver.PipelineStatusBlocked, result.Status)
+
+	_, err = re.Signal("test_signal_without_journal#1", "approval", "approved")
+	assert.ErrorContains(t, err, "journal")
+
+	// the caller keeps the state of the run and rebuilds it with the payload
+	dataContext, pipeline, err := Rebuild(context.Background(), nil, conf, result.State, store,
+		map[string]interface{}{starriver.SignalKey("approval"): "approved"})
+	assert.NoError(t, err)
+	result = re.Run(dataContext, pipeline)
+	assert.Equal(t, starriver.PipelineStatusSuccess, result.Status)
+	assert.Equal(t, starriver.TaskStatusSuccess, result.State["approve"])
+}
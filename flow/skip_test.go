@@ -0,0 +1,98 @@
+package flow
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/thanksloving/starriver"
+)
+
+func TestRun_SkipPropagation(t *testing.T) {
+	pass := starriver.TaskConfigure{Params: []starriver.Param{{Name: "Pass", Type: starriver.ParamTypeLiteral, Literal: true}}}
+	fail := starriver.TaskConfigure{Params: []starriver.Param{{Name: "Pass", Type: starriver.ParamTypeLiteral, Literal: false}}}
+	task := func(id, name string, config starriver.TaskConfigure, depends ...starriver.Depend) starriver.Task {
+		return starriver.Task{ID: id, Name: name, Config: config, Depends: depends}
+	}
+	on := func(id string) starriver.Depend {
+		return starriver.Depend{ID: id}
+	}
+	high := starriver.Depend{ID: "root", Condition: &starriver.Condition{Expr: "score >= 60"}}
+	low := starriver.Depend{ID: "root", Condition: &starriver.Condition{Expr: "score < 60"}}
+	conf := starriver.PipelineConf{
+		Name: "test_skip_propagation",
+		Pipeline: []starriver.Task{
+			task("root", "TestNode", pass),
+			task("high", "TestNode", pass, high),
+			task("high_next", "TestNode", pass, on("high")),
+			task("high_last", "TestNode", pass, on("high_next")),
+			task("low", "TestNode", pass, low),
+			task("any", "@any", starriver.TaskConfigure{}, on("high"), on("low")),
+			task("all", "TestNode", pass, on("high"), on("low")),
+			task("high_any", "@any", starriver.TaskConfigure{}, on("high"), on("high_next")),
+			task("ko", "TestNode", fail, on("low")),
+			task("ko_next", "TestNode", pass, on("ko")),
+			task("ko_last", "TestNode", pass, on("ko_next")),
+		},
+	}
+	pipeline, err := NewPipeline(conf)
+	assert.NoError(t, err)
+	result := NewRiverEngine().Run(NewDataContext(context.Background(), pipeline, map[string]interface{}{"score": 10}), pipeline)
+
+	assert.Equal(t, starriver.PipelineStatusFailure, result.Status)
+	assert.Equal(t, map[string]starriver.TaskStatus{
+		"root":      starriver.TaskStatusSuccess,
+		"high":      starriver.TaskStatusSkipped,
+		"high_next": starriver.TaskStatusSkipped,
+		"high_last": starriver.TaskStatusSkipped,
+		"low":       starriver.TaskStatusSuccess,
+		"any":       starriver.TaskStatusSuccess,
+		"all":       starriver.TaskStatusSkipped,
+		"high_any":  starriver.TaskStatusSkipped,
+		"ko":        starriver.TaskStatusFailure,
+		"ko_next":   starriver.TaskStatusInit,
+		// the default rule only checks the responses of the direct dependencies, ko_next is passed with a warning
+		"ko_last": starriver.TaskStatusSuccess,
+	}, result.State)
+	assert.Equal(t, []string{"all", "high", "high_any", "high_last", "high_next"}, result.Skipped)
+
+	records := make(map[string]starriver.TaskRecord)
+	for _, record := range result.Report.Tasks {
+		records[record.TaskID] = record
+	}
+	assert.Equal(t, starriver.SkipReasonConditionNotMatch, records["high"].SkipReason)
+	assert.Equal(t, starriver.SkipReasonUpstreamSkipped, records["high_next"].SkipReason)
+	assert.Equal(t, starriver.SkipReasonUpstreamSkipped, records["high_last"].SkipReason)
+	assert.Equal(t, starriver.SkipReasonUpstreamSkipped, records["all"].SkipReason)
+	assert.Equal(t, starriver.SkipReasonUpstreamFailed, records["ko_next"].SkipReason)
+	assert.Empty(t, records["ko_last"].SkipReason)
+}
+
+func TestRun_SkipTriggerRule(t *testing.T) {
+	pass := starriver.TaskConfigure{Params: []starriver.Param{{Name: "Pass", Type: starriver.ParamTypeLiteral, Literal: true}}}
+	branch := func(id, branch string) starriver.Task {
+		return starriver.Task{ID: id, Name: "TestNode", Config: pass, Depends: []starriver.Depend{{ID: "route", Branch: branch}}}
+	}
+	join := func(id string, rule starriver.TriggerRule) starriver.Task {
+		return starriver.Task{ID: id, Name: "TestNode", Config: pass, TriggerRule: rule, Depends: []starriver.Depend{{ID: "a"}, {ID: "b"}}}
+	}
+	conf := starriver.PipelineConf{
+		Name: "test_skip_trigger_rule",
+		Pipeline: []starriver.Task{
+			{ID: "route", Name: "@switch", Switch: &starriver.Switch{Key: "kind"}},
+			branch("a", "a"),
+			branch("b", "b"),
+			join("merge", starriver.TriggerRuleNoneFailedMinOneSuccess),
+			join("both", starriver.TriggerRuleAtLeast+"(2)"),
+		},
+	}
+	pipeline, err := NewPipeline(conf)
+	assert.NoError(t, err)
+	result := NewRiverEngine().Run(NewDataContext(context.Background(), pipeline, map[string]interface{}{"kind": "a"}), pipeline)
+
+	assert.Equal(t, starriver.PipelineStatusSuccess, result.Status)
+	assert.Equal(t, starriver.TaskStatusSuccess, result.State["a"])
+	assert.Equal(t, starriver.TaskStatusSuccess, result.State["merge"])
+	assert.Equal(t, []string{"b", "both"}, result.Skipped)
+}
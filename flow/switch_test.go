@@ -0,0 +1,95 @@
+package flow
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/thanksloving/starriver"
+)
+
+func switchConf(sw *starriver.Switch) starriver.PipelineConf {
+	pass := starriver.TaskConfigure{Params: []starriver.Param{{Name: "Pass", Type: starriver.ParamTypeLiteral, Literal: true}}}
+	branch := func(id, branch string) starriver.Task {
+		return starriver.Task{ID: id, Name: "TestNode", Config: pass, Depends: []starriver.Depend{{ID: "route", Branch: branch}}}
+	}
+	return starriver.PipelineConf{
+		Name: "test_switch",
+		Pipeline: []starriver.Task{
+			{ID: "route", Name: "@switch", Switch: sw},
+			branch("vip", "C5"),
+			branch("vip_notify", "C5"),
+			branch("normal", "C1"),
+			branch("fallback", starriver.SwitchDefaultBranch),
+		},
+	}
+}
+
+func TestRun_Switch(t *testing.T) {
+	tests := []struct {
+		name     string
+		sw       *starriver.Switch
+		data     map[string]interface{}
+		selected []string
+	}{
+		{name: "key", sw: &starriver.Switch{Key: "level"}, data: map[string]interface{}{"level": "C5"}, selected: []string{"vip", "vip_notify"}},
+		{name: "expr", sw: &starriver.Switch{Expr: `score >= 60`}, data: map[string]interface{}{"score": 10}, selected: []string{"fallback"}},
+		{name: "default", sw: &starriver.Switch{Key: "level"}, data: map[string]interface{}{"level": "C3"}, selected: []string{"fallback"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conf := switchConf(tt.sw)
+			assert.Empty(t, Validate(conf))
+			pipeline, err := NewPipeline(conf)
+			assert.NoError(t, err)
+			result := NewRiverEngine().Run(NewDataContext(context.Background(), pipeline, tt.data), pipeline)
+			assert.Equal(t, starriver.PipelineStatusSuccess, result.Status)
+			assert.Equal(t, starriver.TaskStatusSuccess, result.State["route"])
+
+			records := make(map[string]starriver.TaskRecord)
+			for _, record := range result.Report.Tasks {
+				records[record.TaskID] = record
+			}
+			for _, task := range []string{"vip", "vip_notify", "normal", "fallback"} {
+				if assert.Contains(t, records, task) && slices.Contains(tt.selected, task) {
+					assert.Equal(t, starriver.TaskStatusSuccess, result.State[task], task)
+					assert.Empty(t, records[task].SkipReason, task)
+				} else {
+					assert.Equal(t, starriver.TaskStatusSkipped, result.State[task], task)
+					assert.Equal(t, starriver.SkipReasonBranchNotTaken, records[task].SkipReason, task)
+				}
+			}
+		})
+	}
+}
+
+func TestRun_SwitchKeyMissing(t *testing.T) {
+	pipeline, err := NewPipeline(switchConf(&starriver.Switch{Key: "level"}))
+	assert.NoError(t, err)
+	result := NewRiverEngine().Run(NewDataContext(context.Background(), pipeline, nil), pipeline)
+	assert.Equal(t, starriver.PipelineStatusFailure, result.Status)
+	assert.Equal(t, starriver.TaskStatusFailure, result.State["route"])
+}
+
+func TestValidate_Switch(t *testing.T) {
+	conf := starriver.PipelineConf{
+		Name: "test_switch",
+		Pipeline: []starriver.Task{
+			{ID: "route", Name: "@switch", Switch: &starriver.Switch{Expr: "level =="}},
+			{ID: "a", Name: "TestNode", Depends: []starriver.Depend{{ID: "route"}}},
+			{ID: "b", Name: "TestNode", Switch: &starriver.Switch{Key: "level"}, Depends: []starriver.Depend{{ID: "a", Branch: "C1"}}},
+		},
+	}
+	errs := Validate(conf)
+	if assert.Len(t, errs, 4) {
+		assert.Equal(t, "pipeline[0].switch.expr", errs[0].Path)
+		assert.Equal(t, "pipeline[2].switch", errs[1].Path)
+		assert.Equal(t, "pipeline[1].depends[0].branch", errs[2].Path)
+		assert.Equal(t, "pipeline[2].depends[0].branch", errs[3].Path)
+	}
+
+	_, err := NewPipeline(switchConf(nil))
+	assert.Error(t, err)
+}
@@ -0,0 +1,131 @@
+package flow
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/thanksloving/starriver"
+)
+
+type recordingTaskEventHandler struct {
+	starriver.NoopTaskEventHandler
+	lock   sync.Mutex
+	events map[string][]string
+}
+
+func (h *recordingTaskEventHandler) record(taskID, event string) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	h.events[taskID] = append(h.events[taskID], event)
+}
+
+func (h *recordingTaskEventHandler) OnTaskReady(dataContext starriver.DataContext, taskID string) {
+	h.record(taskID, "ready")
+}
+
+func (h *recordingTaskEventHandler) OnTaskStart(dataContext starriver.DataContext, taskID string) {
+	h.record(taskID, "start")
+}
+
+func (h *recordingTaskEventHandler) OnTaskEnd(dataContext starriver.DataContext, taskID string, resp starriver.Response, duration time.Duration) {
+	h.record(taskID, fmt.Sprintf("end:%s", resp.GetStatus()))
+}
+
+func (h *recordingTaskEventHandler) OnTaskSkipped(dataContext starriver.DataContext, taskID string, reason starriver.SkipReason) {
+	h.record(taskID, "skipped:"+string(reason))
+}
+
+func (h *recordingTaskEventHandler) OnTaskBlocked(dataContext starriver.DataContext, taskID string, resp starriver.Response) {
+	h.record(taskID, "blocked")
+}
+
+func TestRun_TaskEventHandler(t *testing.T) {
+	pass := starriver.TaskConfigure{
+		Params: []starriver.Param{{Name: "Pass", Type: starriver.ParamTypeLiteral, Literal: true}},
+	}
+	conf := starriver.PipelineConf{
+		Name: "test_task_event",
+		Pipeline: []starriver.Task{
+			{ID: "task1", Name: "TestNode", Config: pass},
+			{
+				ID:      "task2",
+				Name:    "TestNode",
+				Config:  pass,
+				Depends: []starriver.Depend{{ID: "task1", Condition: &starriver.Condition{Key: "score", Value: 60, Operator: starriver.ConditionGT}}},
+			},
+			{
+				ID:      "task3",
+				Name:    "TestNode",
+				Config:  starriver.TaskConfigure{Params: []starriver.Param{{Name: "Pass", Type: starriver.ParamTypeLiteral, Literal: false}}},
+				Depends: []starriver.Depend{{ID: "task1"}},
+			},
+			{ID: "task4", Name: "TestNode", Config: pass, Depends: []starriver.Depend{{ID: "task3"}}},
+			{
+				ID:      "task5",
+				Name:    "TestNode",
+				Config:  starriver.TaskConfigure{SkipExecution: true},
+				Depends: []starriver.Depend{{ID: "task1"}},
+			},
+			{
+				ID:   "task6",
+				Name: "WaitForSignal",
+				Config: starriver.TaskConfigure{
+					Params: []starriver.Param{{Name: "Signal", Type: starriver.ParamTypeLiteral, Literal: "approval"}},
+				},
+				Depends: []starriver.Depend{{ID: "task1"}},
+			},
+		},
+	}
+	handler := &recordingTaskEventHandler{events: make(map[string][]string)}
+	re := NewRiverEngine(SetTaskEventHandler(handler))
+	defer re.Destroy()
+
+	pipeline, err := NewPipeline(conf)
+	assert.NoError(t, err)
+	re.Run(NewDataContext(context.Background(), pipeline, map[string]interface{}{"score": 10}), pipeline)
+
+	assert.Equal(t, map[string][]string{
+		"task1": {"ready", "start", "end:success"},
+		"task2": {"ready", "skipped:condition_not_match"},
+		"task3": {"ready", "start", "end:failure"},
+		"task4": {"skipped:upstream_failed"},
+		"task5": {"ready", "skipped:skip_execution"},
+		"task6": {"ready", "start", "end:blocked", "blocked"},
+	}, handler.events)
+}
+
+func TestRebuild_TaskEventHandler(t *testing.T) {
+	pass := starriver.TaskConfigure{
+		Params: []starriver.Param{{Name: "Pass", Type: starriver.ParamTypeLiteral, Literal: true}},
+	}
+	conf := starriver.PipelineConf{
+		Name: "test_rebuild_task_event",
+		Pipeline: []starriver.Task{
+			{ID: "task1", Name: "TestNode", Config: pass},
+			{ID: "task2", Name: "TestNode", Config: pass, Depends: []starriver.Depend{{ID: "task1"}}},
+		},
+	}
+	handler := &recordingTaskEventHandler{events: make(map[string][]string)}
+	re := NewRiverEngine(SetTaskEventHandler(handler))
+	defer re.Destroy()
+
+	dataContext, pipeline, err := Rebuild(context.Background(), nil, conf, map[string]starriver.TaskStatus{
+		"task1": starriver.TaskStatusSuccess,
+		"task2": starriver.TaskStatusBlocked,
+	}, nil, nil)
+	assert.NoError(t, err)
+	result := re.Run(dataContext, pipeline)
+	assert.NoError(t, result.Error)
+	assert.Equal(t, starriver.PipelineStatusSuccess, result.Status)
+
+	// the task done before the resume is not executed again
+	assert.Equal(t, map[string][]string{
+		"task1": {"ready"},
+		"task2": {"ready", "start", "end:success"},
+	}, handler.events)
+}
@@ -0,0 +1,66 @@
+package flow
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/thanksloving/starriver"
+)
+
+func TestRun_Tracing(t *testing.T) {
+	conf := starriver.PipelineConf{
+		Name: "test_tracing",
+		Pipeline: []starriver.Task{
+			{
+				ID:   "task1",
+				Name: "TestNode",
+				Config: starriver.TaskConfigure{
+					Params: []starriver.Param{{Name: "Pass", Type: starriver.ParamTypeLiteral, Literal: true}},
+				},
+			},
+			{
+				ID:   "task2",
+				Name: "Template",
+				Config: starriver.TaskConfigure{
+					Params: []starriver.Param{
+						{Name: "Template", Type: starriver.ParamTypeVariable, Variable: "missing", Required: true},
+					},
+				},
+				Depends: []starriver.Depend{{ID: "task1"}},
+			},
+		},
+	}
+	tracer := starriver.NewRecordingTracer()
+	re := NewRiverEngine(SetTracer(tracer))
+	defer re.Destroy()
+
+	pipeline, err := NewPipeline(conf)
+	assert.NoError(t, err)
+	dc := NewDataContext(context.Background(), pipeline, nil, SetRequestID("1"))
+	result := re.Run(dc, pipeline)
+	assert.Equal(t, starriver.PipelineStatusFailure, result.Status)
+
+	spans := make(map[string]starriver.RecordedSpan)
+	for _, span := range tracer.Spans() {
+		assert.False(t, span.EndTime.IsZero(), span.Name)
+		spans[span.Name] = span
+	}
+	root := spans["pipeline test_tracing"]
+	assert.Equal(t, 0, root.ParentID)
+	assert.Equal(t, "test_tracing#1", root.Attributes[starriver.AttrRequestID])
+	assert.Equal(t, string(starriver.PipelineStatusFailure), root.Attributes[starriver.AttrPipelineStatus])
+	assert.NotEmpty(t, root.Errors)
+
+	task1 := spans["task task1"]
+	assert.Equal(t, root.ID, task1.ParentID)
+	assert.Equal(t, "TestNode", task1.Attributes[starriver.AttrComponent])
+	assert.Equal(t, string(starriver.TaskStatusSuccess), task1.Attributes[starriver.AttrTaskStatus])
+	assert.Equal(t, int64(starriver.FailureLevelNormal), task1.Attributes[starriver.AttrFailureLevel])
+	assert.Equal(t, 1, task1.Attributes[starriver.AttrAttempts])
+
+	task2 := spans["task task2"]
+	assert.Equal(t, string(starriver.TaskStatusFailure), task2.Attributes[starriver.AttrTaskStatus])
+	assert.Contains(t, task2.Attributes[starriver.AttrParamError], "missing")
+}
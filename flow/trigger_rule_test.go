@@ -0,0 +1,72 @@
+package flow
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/thanksloving/starriver"
+)
+
+func TestRun_TriggerRule(t *testing.T) {
+	pass := starriver.TaskConfigure{Params: []starriver.Param{{Name: "Pass", Type: starriver.ParamTypeLiteral, Literal: true}}}
+	fail := starriver.TaskConfigure{Params: []starriver.Param{{Name: "Pass", Type: starriver.ParamTypeLiteral, Literal: false}}}
+	task := func(id string, config starriver.TaskConfigure, rule starriver.TriggerRule, depends ...string) starriver.Task {
+		t := starriver.Task{ID: id, Name: "TestNode", Config: config, TriggerRule: rule}
+		for _, depend := range depends {
+			t.Depends = append(t.Depends, starriver.Depend{ID: depend})
+		}
+		return t
+	}
+	conf := starriver.PipelineConf{
+		Name: "test_trigger_rule",
+		Pipeline: []starriver.Task{
+			task("root", pass, ""),
+			task("ok", pass, "", "root"),
+			task("ko", fail, "", "root"),
+			task("join", pass, starriver.TriggerRuleOneSuccess, "ok", "ko"),
+			task("cleanup", pass, starriver.TriggerRuleAllDone, "ok", "ko"),
+			task("alert", pass, starriver.TriggerRuleOneFailed, "ok", "ko"),
+			task("no_alert", pass, starriver.TriggerRuleOneFailed, "root", "ok"),
+			task("all", pass, starriver.TriggerRuleNoneFailed, "ok", "ko"),
+			task("two", pass, "at_least(2)", "root", "ok"),
+		},
+	}
+	assert.Empty(t, Validate(conf))
+	pipeline, err := NewPipeline(conf)
+	assert.NoError(t, err)
+	result := NewRiverEngine().Run(NewDataContext(context.Background(), pipeline, nil), pipeline)
+
+	assert.Equal(t, starriver.TaskStatusSuccess, result.State["join"])
+	assert.Equal(t, starriver.TaskStatusSuccess, result.State["cleanup"])
+	assert.Equal(t, starriver.TaskStatusSuccess, result.State["alert"])
+	assert.Equal(t, starriver.TaskStatusInit, result.State["no_alert"])
+	assert.Equal(t, starriver.TaskStatusInit, result.State["all"])
+	assert.Equal(t, starriver.TaskStatusSuccess, result.State["two"])
+	// the failure is tolerated by one_success like @any
+	assert.Equal(t, starriver.PipelineStatusSuccess, result.Status)
+
+	records := make(map[string]starriver.TaskRecord)
+	for _, record := range result.Report.Tasks {
+		records[record.TaskID] = record
+	}
+	assert.Equal(t, starriver.SkipReasonUpstreamFailed, records["no_alert"].SkipReason)
+	assert.Equal(t, starriver.SkipReasonUpstreamFailed, records["all"].SkipReason)
+}
+
+func TestValidate_TriggerRule(t *testing.T) {
+	conf := starriver.PipelineConf{
+		Name: "test_trigger_rule",
+		Pipeline: []starriver.Task{
+			{ID: "root", Name: "TestNode", TriggerRule: "at_least(0)"},
+			{ID: "any", Name: "@any", TriggerRule: starriver.TriggerRuleAllDone, Depends: []starriver.Depend{{ID: "root"}}},
+		},
+	}
+	errs := Validate(conf)
+	assert.Len(t, errs, 2)
+	assert.Equal(t, "pipeline[0].trigger_rule", errs[0].Path)
+	assert.Equal(t, "pipeline[1].trigger_rule", errs[1].Path)
+	_, err := NewPipeline(conf)
+	assert.Error(t, err)
+}
@@ -76,6 +76,16 @@ func NewWarnResponse(err error) starriver.Response {
 	}
 }
 
+// NewSkippedResponse the task is not executed because it's not selected, err tells why
+func NewSkippedResponse(err error) starriver.Response {
+	return &response{
+		Pass:         true,
+		FailureLevel: starriver.FailureLevelNormal,
+		Error:        err,
+		Status:       starriver.TaskStatusSkipped,
+	}
+}
+
 func NewBlockedResponse() starriver.Response {
 	return &response{
 		Pass:         false,
@@ -13,6 +13,7 @@ type (
 		lock     sync.RWMutex `json:"-" yaml:"-" msgpack:"-"`
 		nodeLock sync.RWMutex `json:"-" yaml:"-"  msgpack:"-"`
 		Codec    Codec        `json:"-" yaml:"-" msgpack:"-"`
+		fsync    bool         // sync every write of the persistent data store to the disk
 		dataStore
 	}
 
@@ -0,0 +1,197 @@
+package builtin
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/thanksloving/starriver"
+)
+
+const (
+	opSet  = "set"
+	opDel  = "del"
+	opNode = "node"
+	opLoad = "load"
+)
+
+type (
+	// fileSharedDataStore keeps the Data in memory, and appends every change to a local file,
+	// so the Data of a run can be recovered from the file after the process crashed.
+	fileSharedDataStore struct {
+		*defaultSharedDataStore
+		dir       string
+		path      string
+		file      *os.File // opened by the first write after Configure, and closed by Close
+		writeLock sync.Mutex
+	}
+
+	// record is one change of the data store, each record is written as a length-prefixed frame
+	record struct {
+		Op       string                            `json:"op" yaml:"op" msgpack:"op"`
+		Key      string                            `json:"key,omitempty" yaml:"key,omitempty" msgpack:"key,omitempty"`
+		Value    interface{}                       `json:"value,omitempty" yaml:"value,omitempty" msgpack:"value,omitempty"`
+		NodeData map[string]map[string]interface{} `json:"node_data,omitempty" yaml:"node_data,omitempty" msgpack:"node_data,omitempty"`
+		Data     map[string]interface{}            `json:"data,omitempty" yaml:"data,omitempty" msgpack:"data,omitempty"`
+	}
+)
+
+var (
+	_ starriver.SharedDataStore = (*fileSharedDataStore)(nil)
+
+	unsafePathChars = regexp.MustCompile(`[^a-zA-Z0-9_.\-]`)
+)
+
+// NewFileSharedDataStore returns a data store persisted under dir, the file of a run is located by the flowName and requestID
+// passed to Configure. Configure a new store with the same flowName and requestID will recover the Data from the file.
+func NewFileSharedDataStore(dir string, options ...DataSourceOption) starriver.SharedDataStore {
+	return &fileSharedDataStore{
+		defaultSharedDataStore: NewSharedDataStore(options...).(*defaultSharedDataStore),
+		dir:                    dir,
+	}
+}
+
+// WithFsync syncs every write of the file data store to the disk, so the data survives the crash of the machine as well
+// as the process. It's off by default, since a sync per write is slow.
+func WithFsync() DataSourceOption {
+	return func(ds *defaultSharedDataStore) {
+		ds.fsync = true
+	}
+}
+
+// FileSharedDataStorePath returns the file of the run stored under dir
+func FileSharedDataStorePath(dir, flowName, requestID string) string {
+	return filepath.Join(dir, unsafePathChars.ReplaceAllString(flowName, "_"), unsafePathChars.ReplaceAllString(requestID, "_")+".log")
+}
+
+func (fds *fileSharedDataStore) Configure(flowName, requestID string) {
+	fds.writeLock.Lock()
+	defer fds.writeLock.Unlock()
+	if err := fds.closeFile(); err != nil {
+		logrus.Errorf("[FileSharedDataStore] close %q error %v", fds.path, err)
+	}
+	fds.path = FileSharedDataStorePath(fds.dir, flowName, requestID)
+	if err := os.MkdirAll(filepath.Dir(fds.path), 0o755); err != nil {
+		logrus.Errorf("[FileSharedDataStore] create dir of %q error %v", fds.path, err)
+		return
+	}
+	if err := fds.replay(); err != nil {
+		logrus.Errorf("[FileSharedDataStore] replay %q error %v", fds.path, err)
+	}
+}
+
+func (fds *fileSharedDataStore) Set(ctx context.Context, key string, value interface{}) bool {
+	fds.writeLock.Lock()
+	defer fds.writeLock.Unlock()
+	ok := fds.defaultSharedDataStore.Set(ctx, key, value)
+	fds.append(record{Op: opSet, Key: key, Value: value})
+	return ok
+}
+
+func (fds *fileSharedDataStore) Del(ctx context.Context, key string) {
+	fds.writeLock.Lock()
+	defer fds.writeLock.Unlock()
+	fds.defaultSharedDataStore.Del(ctx, key)
+	fds.append(record{Op: opDel, Key: key})
+}
+
+func (fds *fileSharedDataStore) SetCurrentNodeData(ctx context.Context, nodeId string, data map[string]interface{}) {
+	fds.writeLock.Lock()
+	defer fds.writeLock.Unlock()
+	fds.defaultSharedDataStore.SetCurrentNodeData(ctx, nodeId, data)
+	fds.append(record{Op: opNode, Key: nodeId, Data: data})
+}
+
+// Unmarshal replaces all the Data with the snapshot, and persists it as well
+func (fds *fileSharedDataStore) Unmarshal(data []byte) error {
+	fds.writeLock.Lock()
+	defer fds.writeLock.Unlock()
+	if err := fds.defaultSharedDataStore.Unmarshal(data); err != nil {
+		return err
+	}
+	fds.append(record{Op: opLoad, Data: fds.Data, NodeData: fds.NodeData})
+	return nil
+}
+
+// Close closes the file of the run, the data context closes it on Release. The store can still be used, the file is
+// opened again by the next write.
+func (fds *fileSharedDataStore) Close() error {
+	fds.writeLock.Lock()
+	defer fds.writeLock.Unlock()
+	return fds.closeFile()
+}
+
+func (fds *fileSharedDataStore) closeFile() error {
+	if fds.file == nil {
+		return nil
+	}
+	err := fds.file.Close()
+	fds.file = nil
+	return err
+}
+
+// append writes the record to the file, it does nothing before Configure
+func (fds *fileSharedDataStore) append(r record) {
+	if fds.path == "" {
+		return
+	}
+	if err := fds.write(r); err != nil {
+		logrus.Errorf("[FileSharedDataStore] append %s %q to %q error %v", r.Op, r.Key, fds.path, err)
+	}
+}
+
+func (fds *fileSharedDataStore) write(r record) error {
+	payload, err := fds.Codec.Marshal(r)
+	if err != nil {
+		return err
+	}
+	if fds.file == nil {
+		if fds.file, err = os.OpenFile(fds.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644); err != nil {
+			return err
+		}
+	}
+	if err = writeFrame(fds.file, payload); err != nil || !fds.fsync {
+		return err
+	}
+	return fds.file.Sync()
+}
+
+// replay loads all the records of the file
+func (fds *fileSharedDataStore) replay() error {
+	return readFrames(fds.path, func(payload []byte) error {
+		var r record
+		if err := fds.Codec.Unmarshal(payload, &r); err != nil {
+			return err
+		}
+		fds.apply(r)
+		return nil
+	})
+}
+
+func (fds *fileSharedDataStore) apply(r record) {
+	ctx := context.Background()
+	switch r.Op {
+	case opSet:
+		fds.defaultSharedDataStore.Set(ctx, r.Key, r.Value)
+	case opDel:
+		fds.defaultSharedDataStore.Del(ctx, r.Key)
+	case opNode:
+		fds.defaultSharedDataStore.SetCurrentNodeData(ctx, r.Key, r.Data)
+	case opLoad:
+		fds.lock.Lock()
+		fds.nodeLock.Lock()
+		fds.dataStore = dataStore{Data: r.Data, NodeData: r.NodeData}
+		if fds.Data == nil {
+			fds.Data = make(map[string]interface{})
+		}
+		if fds.NodeData == nil {
+			fds.NodeData = make(map[string]map[string]interface{})
+		}
+		fds.nodeLock.Unlock()
+		fds.lock.Unlock()
+	}
+}
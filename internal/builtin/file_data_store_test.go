@@ -0,0 +1,107 @@
+package builtin
+
+import (
+	"context"
+	"io"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFileSharedDataStore_Recover(t *testing.T) {
+	dir := t.TempDir()
+	ctx := context.Background()
+	ds := NewFileSharedDataStore(dir)
+	ds.Configure("flow/test", "request#1")
+	ds.Set(ctx, "a", "x")
+	ds.Set(ctx, "b", "y")
+	ds.Del(ctx, "b")
+	ds.SetCurrentNodeData(ctx, "task1", map[string]interface{}{"c": true})
+
+	recovered := NewFileSharedDataStore(dir)
+	recovered.Configure("flow/test", "request#1")
+	val, ok := recovered.Get(ctx, "a")
+	assert.True(t, ok)
+	assert.Equal(t, "x", val)
+	_, ok = recovered.Get(ctx, "b")
+	assert.False(t, ok)
+	val, ok = recovered.GetDependNodeValue(ctx, "task1", "c")
+	assert.True(t, ok)
+	assert.Equal(t, true, val)
+
+	other := NewFileSharedDataStore(dir)
+	other.Configure("flow/test", "request#2")
+	_, ok = other.Get(ctx, "a")
+	assert.False(t, ok)
+}
+
+func TestFileSharedDataStore_BrokenTail(t *testing.T) {
+	dir := t.TempDir()
+	ctx := context.Background()
+	ds := NewFileSharedDataStore(dir)
+	ds.Configure("flow", "request")
+	ds.Set(ctx, "a", "x")
+
+	path := FileSharedDataStorePath(dir, "flow", "request")
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0o644)
+	assert.NoError(t, err)
+	_, err = f.Write([]byte{0, 0, 0, 100, '{'})
+	assert.NoError(t, err)
+	assert.NoError(t, f.Close())
+
+	recovered := NewFileSharedDataStore(dir)
+	recovered.Configure("flow", "request")
+	recovered.Set(ctx, "b", "y")
+
+	again := NewFileSharedDataStore(dir)
+	again.Configure("flow", "request")
+	for key, expected := range map[string]string{"a": "x", "b": "y"} {
+		val, ok := again.Get(ctx, key)
+		assert.True(t, ok)
+		assert.Equal(t, expected, val)
+	}
+}
+
+func TestFileSharedDataStore_Unmarshal(t *testing.T) {
+	dir := t.TempDir()
+	ctx := context.Background()
+	snapshot := NewSharedDataStore()
+	snapshot.Set(ctx, "a", "x")
+	bs, err := snapshot.Marshal()
+	assert.NoError(t, err)
+
+	ds := NewFileSharedDataStore(dir)
+	ds.Configure("flow", "request")
+	assert.NoError(t, ds.Unmarshal(bs))
+
+	recovered := NewFileSharedDataStore(dir)
+	recovered.Configure("flow", "request")
+	val, ok := recovered.Get(ctx, "a")
+	assert.True(t, ok)
+	assert.Equal(t, "x", val)
+}
+
+func TestFileSharedDataStore_Close(t *testing.T) {
+	dir := t.TempDir()
+	ctx := context.Background()
+	ds := NewFileSharedDataStore(dir, WithFsync())
+	ds.Configure("flow", "request")
+	ds.Set(ctx, "a", "x")
+	closer, ok := ds.(io.Closer)
+	assert.True(t, ok)
+	assert.NoError(t, closer.Close())
+	assert.NoError(t, closer.Close())
+
+	// the file is opened again by the next write
+	ds.Set(ctx, "b", "y")
+	assert.NoError(t, closer.Close())
+
+	recovered := NewFileSharedDataStore(dir)
+	recovered.Configure("flow", "request")
+	for key, expected := range map[string]string{"a": "x", "b": "y"} {
+		val, ok := recovered.Get(ctx, key)
+		assert.True(t, ok)
+		assert.Equal(t, expected, val)
+	}
+}
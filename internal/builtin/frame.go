@@ -0,0 +1,67 @@
+package builtin
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"os"
+
+	"github.com/sirupsen/logrus"
+)
+
+// appendFrame appends the payload to the file as a length-prefixed frame, and syncs it to the disk
+func appendFrame(path string, payload []byte) error {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	if err = writeFrame(f, payload); err == nil {
+		err = f.Sync()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// writeFrame writes the payload as a length-prefixed frame with a single write
+func writeFrame(w io.Writer, payload []byte) error {
+	frame := make([]byte, 4+len(payload))
+	binary.BigEndian.PutUint32(frame, uint32(len(payload)))
+	copy(frame[4:], payload)
+	_, err := w.Write(frame)
+	return err
+}
+
+// readFrames calls fn with every frame of the file, an incomplete frame at the end (the process crashed while writing)
+// is dropped from the file.
+func readFrames(path string, fn func(payload []byte) error) error {
+	content, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	reader := bytes.NewReader(content)
+	var offset int64
+	for {
+		var size uint32
+		if err = binary.Read(reader, binary.BigEndian, &size); err != nil {
+			break
+		}
+		payload := make([]byte, size)
+		if _, err = io.ReadFull(reader, payload); err != nil {
+			break
+		}
+		if err = fn(payload); err != nil {
+			break
+		}
+		offset += int64(4 + size)
+	}
+	if offset < int64(len(content)) {
+		logrus.Warnf("drop the broken tail of %q at %d, err=%v", path, offset, err)
+		return os.Truncate(path, offset)
+	}
+	return nil
+}
@@ -0,0 +1,248 @@
+package builtin
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/thanksloving/starriver"
+)
+
+const (
+	entryStart = "start"
+	entryTask  = "task"
+	entryEnd   = "end"
+
+	journalExt = ".journal"
+)
+
+type (
+	// journalEntry is one event of a run
+	journalEntry struct {
+		Type     string                 `json:"type" yaml:"type" msgpack:"type"`
+		Run      *starriver.RunRecord   `json:"run,omitempty" yaml:"run,omitempty" msgpack:"run,omitempty"`
+		TaskID   string                 `json:"task_id,omitempty" yaml:"task_id,omitempty" msgpack:"task_id,omitempty"`
+		Status   string                 `json:"status,omitempty" yaml:"status,omitempty" msgpack:"status,omitempty"`
+		Data     map[string]interface{} `json:"data,omitempty" yaml:"data,omitempty" msgpack:"data,omitempty"`
+		Snapshot []byte                 `json:"snapshot,omitempty" yaml:"snapshot,omitempty" msgpack:"snapshot,omitempty"`
+		Time     time.Time              `json:"time" yaml:"time" msgpack:"time"`
+	}
+
+	memoryJournal struct {
+		lock sync.RWMutex
+		runs map[string]*starriver.RunRecord
+	}
+
+	// fileJournal appends the entries of each run to its own file under dir
+	fileJournal struct {
+		dir   string
+		lock  sync.Mutex
+		Codec Codec
+	}
+
+	JournalOption func(*fileJournal)
+)
+
+var (
+	_ starriver.RunJournal = (*memoryJournal)(nil)
+	_ starriver.RunJournal = (*fileJournal)(nil)
+)
+
+// apply updates the run with the entry, it returns the run started by the entry if the run is nil
+func (e journalEntry) apply(run *starriver.RunRecord) *starriver.RunRecord {
+	switch e.Type {
+	case entryStart:
+		if run == nil {
+			run = &starriver.RunRecord{RequestID: e.Run.RequestID}
+		}
+		run.Conf = e.Run.Conf
+		run.Snapshot = e.Run.Snapshot
+		run.Status = e.Run.Status
+		run.StartedAt = e.Time
+		run.Finished = false
+		run.EndedAt = time.Time{}
+		if run.State == nil {
+			run.State = make(map[string]starriver.TaskStatus)
+		}
+		if run.NodeData == nil {
+			run.NodeData = make(map[string]map[string]interface{})
+		}
+	case entryTask:
+		if run == nil {
+			return nil
+		}
+		run.State[e.TaskID] = starriver.TaskStatus(e.Status)
+		if len(e.Data) > 0 {
+			run.NodeData[e.TaskID] = e.Data
+		}
+	case entryEnd:
+		if run == nil {
+			return nil
+		}
+		run.Status = starriver.PipelineStatus(e.Status)
+		run.Finished = true
+		run.EndedAt = e.Time
+		if e.Snapshot != nil {
+			run.Snapshot = e.Snapshot
+		}
+	}
+	return run
+}
+
+func copyRunRecord(run *starriver.RunRecord) *starriver.RunRecord {
+	cp := *run
+	cp.State = make(map[string]starriver.TaskStatus, len(run.State))
+	for k, v := range run.State {
+		cp.State[k] = v
+	}
+	cp.NodeData = make(map[string]map[string]interface{}, len(run.NodeData))
+	for k, v := range run.NodeData {
+		cp.NodeData[k] = v
+	}
+	return &cp
+}
+
+func sortRunRecords(runs []*starriver.RunRecord) {
+	sort.Slice(runs, func(i, j int) bool {
+		return runs[i].StartedAt.Before(runs[j].StartedAt)
+	})
+}
+
+// NewMemoryJournal returns a journal in memory, it's useful for test or resuming the blocked runs in the same process.
+func NewMemoryJournal() starriver.RunJournal {
+	return &memoryJournal{runs: make(map[string]*starriver.RunRecord)}
+}
+
+func (mj *memoryJournal) record(requestID string, e journalEntry) error {
+	mj.lock.Lock()
+	defer mj.lock.Unlock()
+	if run := e.apply(mj.runs[requestID]); run != nil {
+		mj.runs[requestID] = run
+	}
+	return nil
+}
+
+func (mj *memoryJournal) Start(run starriver.RunRecord) error {
+	return mj.record(run.RequestID, journalEntry{Type: entryStart, Run: &run, Time: time.Now()})
+}
+
+func (mj *memoryJournal) Task(requestID, taskID string, status starriver.TaskStatus, data map[string]interface{}) error {
+	return mj.record(requestID, journalEntry{Type: entryTask, TaskID: taskID, Status: string(status), Data: data, Time: time.Now()})
+}
+
+func (mj *memoryJournal) End(requestID string, status starriver.PipelineStatus, snapshot []byte) error {
+	return mj.record(requestID, journalEntry{Type: entryEnd, Status: string(status), Snapshot: snapshot, Time: time.Now()})
+}
+
+func (mj *memoryJournal) Get(requestID string) (*starriver.RunRecord, error) {
+	mj.lock.RLock()
+	defer mj.lock.RUnlock()
+	if run, ok := mj.runs[requestID]; ok {
+		return copyRunRecord(run), nil
+	}
+	return nil, nil
+}
+
+func (mj *memoryJournal) Unfinished() ([]*starriver.RunRecord, error) {
+	mj.lock.RLock()
+	defer mj.lock.RUnlock()
+	runs := make([]*starriver.RunRecord, 0)
+	for _, run := range mj.runs {
+		if !run.Finished {
+			runs = append(runs, copyRunRecord(run))
+		}
+	}
+	sortRunRecords(runs)
+	return runs, nil
+}
+
+// NewFileJournal returns a journal persisted under dir, every entry is synced to the disk before return.
+func NewFileJournal(dir string, options ...JournalOption) (starriver.RunJournal, error) {
+	fj := &fileJournal{dir: dir}
+	for _, option := range options {
+		option(fj)
+	}
+	if fj.Codec == nil {
+		fj.Codec = &defaultCodec{}
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return fj, nil
+}
+
+func WithJournalCodec(codec Codec) JournalOption {
+	return func(fj *fileJournal) {
+		fj.Codec = codec
+	}
+}
+
+func (fj *fileJournal) path(requestID string) string {
+	return filepath.Join(fj.dir, unsafePathChars.ReplaceAllString(requestID, "_")+journalExt)
+}
+
+func (fj *fileJournal) record(requestID string, e journalEntry) error {
+	payload, err := fj.Codec.Marshal(e)
+	if err != nil {
+		return err
+	}
+	fj.lock.Lock()
+	defer fj.lock.Unlock()
+	return appendFrame(fj.path(requestID), payload)
+}
+
+func (fj *fileJournal) load(path string) (*starriver.RunRecord, error) {
+	fj.lock.Lock()
+	defer fj.lock.Unlock()
+	var run *starriver.RunRecord
+	err := readFrames(path, func(payload []byte) error {
+		var e journalEntry
+		if err := fj.Codec.Unmarshal(payload, &e); err != nil {
+			return err
+		}
+		run = e.apply(run)
+		return nil
+	})
+	return run, err
+}
+
+func (fj *fileJournal) Start(run starriver.RunRecord) error {
+	return fj.record(run.RequestID, journalEntry{Type: entryStart, Run: &run, Time: time.Now()})
+}
+
+func (fj *fileJournal) Task(requestID, taskID string, status starriver.TaskStatus, data map[string]interface{}) error {
+	return fj.record(requestID, journalEntry{Type: entryTask, TaskID: taskID, Status: string(status), Data: data, Time: time.Now()})
+}
+
+func (fj *fileJournal) End(requestID string, status starriver.PipelineStatus, snapshot []byte) error {
+	return fj.record(requestID, journalEntry{Type: entryEnd, Status: string(status), Snapshot: snapshot, Time: time.Now()})
+}
+
+func (fj *fileJournal) Get(requestID string) (*starriver.RunRecord, error) {
+	return fj.load(fj.path(requestID))
+}
+
+func (fj *fileJournal) Unfinished() ([]*starriver.RunRecord, error) {
+	entries, err := os.ReadDir(fj.dir)
+	if err != nil {
+		return nil, err
+	}
+	runs := make([]*starriver.RunRecord, 0)
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), journalExt) {
+			continue
+		}
+		run, err := fj.load(filepath.Join(fj.dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		if run != nil && !run.Finished {
+			runs = append(runs, run)
+		}
+	}
+	sortRunRecords(runs)
+	return runs, nil
+}
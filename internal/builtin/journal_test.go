@@ -0,0 +1,46 @@
+package builtin
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/thanksloving/starriver"
+)
+
+func TestJournal(t *testing.T) {
+	fileJournal, err := NewFileJournal(t.TempDir())
+	assert.NoError(t, err)
+	for _, journal := range []starriver.RunJournal{NewMemoryJournal(), fileJournal} {
+		conf := starriver.PipelineConf{Name: "flow"}
+		assert.NoError(t, journal.Start(starriver.RunRecord{RequestID: "flow#1", Conf: conf, Snapshot: []byte("s1")}))
+		assert.NoError(t, journal.Start(starriver.RunRecord{RequestID: "flow#2", Conf: conf}))
+		assert.NoError(t, journal.Task("flow#1", "task1", starriver.TaskStatusSuccess, map[string]interface{}{"a": "b"}))
+		assert.NoError(t, journal.Task("flow#1", "task2", starriver.TaskStatusBlocked, nil))
+		assert.NoError(t, journal.End("flow#1", starriver.PipelineStatusBlocked, []byte("s2")))
+
+		run, err := journal.Get("flow#1")
+		assert.NoError(t, err)
+		assert.True(t, run.Finished)
+		assert.Equal(t, starriver.PipelineStatusBlocked, run.Status)
+		assert.Equal(t, []byte("s2"), run.Snapshot)
+		assert.Equal(t, map[string]starriver.TaskStatus{"task1": starriver.TaskStatusSuccess, "task2": starriver.TaskStatusBlocked}, run.State)
+		assert.Equal(t, "b", run.NodeData["task1"]["a"])
+
+		unfinished, err := journal.Unfinished()
+		assert.NoError(t, err)
+		assert.Len(t, unfinished, 1)
+		assert.Equal(t, "flow#2", unfinished[0].RequestID)
+
+		// resume keeps the status of tasks
+		assert.NoError(t, journal.Start(starriver.RunRecord{RequestID: "flow#1", Conf: conf}))
+		run, err = journal.Get("flow#1")
+		assert.NoError(t, err)
+		assert.False(t, run.Finished)
+		assert.Equal(t, starriver.TaskStatusSuccess, run.State["task1"])
+
+		run, err = journal.Get("flow#3")
+		assert.NoError(t, err)
+		assert.Nil(t, run)
+	}
+}
@@ -0,0 +1,189 @@
+package builtin
+
+import (
+	"bufio"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/thanksloving/starriver"
+)
+
+const metricsNamespace = "starriver"
+
+type (
+	// PrometheusCollector keeps the metrics in memory, and writes them in the Prometheus text format when it's served
+	PrometheusCollector interface {
+		starriver.MetricsCollector
+		http.Handler
+	}
+
+	prometheusCollector struct {
+		lock     sync.Mutex
+		families []*metricFamily
+
+		pipelineRuns     *metricFamily
+		pipelineDuration *metricFamily
+		taskRuns         *metricFamily
+		taskDuration     *metricFamily
+		semaphoreWait    *metricFamily
+	}
+
+	metricFamily struct {
+		name    string
+		help    string
+		counter bool
+		labels  []string
+		buckets []float64
+		series  map[string]*metricSeries
+	}
+
+	// metricSeries is a counter if the family has no buckets, otherwise a histogram
+	metricSeries struct {
+		labelValues []string
+		counts      []uint64 // cumulative count of each bucket
+		count       uint64
+		sum         float64
+	}
+)
+
+var (
+	_ PrometheusCollector = (*prometheusCollector)(nil)
+
+	// DefaultBuckets in seconds, from 1ms to 60s
+	DefaultBuckets = []float64{0.001, 0.005, 0.01, 0.05, 0.1, 0.5, 1, 5, 10, 30, 60}
+
+	labelValueEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+)
+
+// NewPrometheusCollector returns a collector without any dependency of the Prometheus client, the buckets are in seconds,
+// DefaultBuckets are used if buckets is empty.
+func NewPrometheusCollector(buckets ...float64) PrometheusCollector {
+	if len(buckets) == 0 {
+		buckets = DefaultBuckets
+	}
+	buckets = append([]float64(nil), buckets...)
+	sort.Float64s(buckets)
+	pc := &prometheusCollector{}
+	pc.pipelineRuns = pc.counter("pipeline_runs_total", "Total number of the pipeline runs.", "pipeline", "status")
+	pc.pipelineDuration = pc.histogram("pipeline_duration_seconds", "Duration of the pipeline runs.", buckets, "pipeline", "status")
+	pc.taskRuns = pc.counter("task_runs_total", "Total number of the task executions.", "pipeline", "task", "component", "status", "failure_level")
+	pc.taskDuration = pc.histogram("task_duration_seconds", "Duration of the task executions including retries.", buckets, "pipeline", "task", "component")
+	pc.semaphoreWait = pc.histogram("semaphore_wait_seconds", "Time waited to acquire the semaphores.", buckets, "pipeline", "semaphore")
+	return pc
+}
+
+func (pc *prometheusCollector) counter(name, help string, labels ...string) *metricFamily {
+	family := &metricFamily{name: metricsNamespace + "_" + name, help: help, counter: true, labels: labels, series: make(map[string]*metricSeries)}
+	pc.families = append(pc.families, family)
+	return family
+}
+
+func (pc *prometheusCollector) histogram(name, help string, buckets []float64, labels ...string) *metricFamily {
+	family := &metricFamily{name: metricsNamespace + "_" + name, help: help, labels: labels, buckets: buckets, series: make(map[string]*metricSeries)}
+	pc.families = append(pc.families, family)
+	return family
+}
+
+func (pc *prometheusCollector) ObservePipeline(pipeline string, status starriver.PipelineStatus, duration time.Duration) {
+	pc.lock.Lock()
+	defer pc.lock.Unlock()
+	pc.pipelineRuns.observe(1, pipeline, string(status))
+	pc.pipelineDuration.observe(duration.Seconds(), pipeline, string(status))
+}
+
+func (pc *prometheusCollector) ObserveTask(pipeline, taskID, component string, status starriver.TaskStatus,
+	level starriver.FailureLevel, duration time.Duration) {
+	pc.lock.Lock()
+	defer pc.lock.Unlock()
+	pc.taskRuns.observe(1, pipeline, taskID, component, string(status), strconv.FormatInt(int64(level), 10))
+	pc.taskDuration.observe(duration.Seconds(), pipeline, taskID, component)
+}
+
+func (pc *prometheusCollector) ObserveSemaphoreWait(pipeline, semaphore string, wait time.Duration) {
+	pc.lock.Lock()
+	defer pc.lock.Unlock()
+	pc.semaphoreWait.observe(wait.Seconds(), pipeline, semaphore)
+}
+
+// ServeHTTP writes all the metrics in the Prometheus text format
+func (pc *prometheusCollector) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	bw := bufio.NewWriter(w)
+	pc.lock.Lock()
+	for _, family := range pc.families {
+		family.write(bw)
+	}
+	pc.lock.Unlock()
+	_ = bw.Flush()
+}
+
+// observe adds the value to the counter, or puts it into the histogram
+func (mf *metricFamily) observe(value float64, labelValues ...string) {
+	key := strings.Join(labelValues, "\xff")
+	series, ok := mf.series[key]
+	if !ok {
+		series = &metricSeries{labelValues: labelValues, counts: make([]uint64, len(mf.buckets))}
+		mf.series[key] = series
+	}
+	series.sum += value
+	series.count++
+	for idx, bound := range mf.buckets {
+		if value <= bound {
+			series.counts[idx]++
+		}
+	}
+}
+
+func (mf *metricFamily) write(w *bufio.Writer) {
+	if len(mf.series) == 0 {
+		return
+	}
+	typ := "histogram"
+	if mf.counter {
+		typ = "counter"
+	}
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", mf.name, mf.help, mf.name, typ)
+	keys := make([]string, 0, len(mf.series))
+	for key := range mf.series {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		series := mf.series[key]
+		labels := mf.formatLabels(series.labelValues)
+		if mf.counter {
+			fmt.Fprintf(w, "%s{%s} %s\n", mf.name, labels, formatFloat(series.sum))
+			continue
+		}
+		for idx, bound := range mf.buckets {
+			fmt.Fprintf(w, "%s_bucket{%s,le=\"%s\"} %d\n", mf.name, labels, formatFloat(bound), series.counts[idx])
+		}
+		fmt.Fprintf(w, "%s_bucket{%s,le=\"+Inf\"} %d\n", mf.name, labels, series.count)
+		fmt.Fprintf(w, "%s_sum{%s} %s\n", mf.name, labels, formatFloat(series.sum))
+		fmt.Fprintf(w, "%s_count{%s} %d\n", mf.name, labels, series.count)
+	}
+}
+
+func (mf *metricFamily) formatLabels(labelValues []string) string {
+	pairs := make([]string, len(mf.labels))
+	for idx, label := range mf.labels {
+		pairs[idx] = fmt.Sprintf("%s=\"%s\"", label, labelValueEscaper.Replace(labelValues[idx]))
+	}
+	return strings.Join(pairs, ",")
+}
+
+func formatFloat(f float64) string {
+	switch {
+	case math.IsInf(f, 1):
+		return "+Inf"
+	case math.IsInf(f, -1):
+		return "-Inf"
+	}
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
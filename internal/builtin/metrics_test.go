@@ -0,0 +1,50 @@
+package builtin
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/thanksloving/starriver"
+)
+
+func TestPrometheusCollector(t *testing.T) {
+	collector := NewPrometheusCollector(0.1, 1)
+	collector.ObservePipeline("demo", starriver.PipelineStatusSuccess, 500*time.Millisecond)
+	collector.ObserveTask("demo", "task1", "Template", starriver.TaskStatusSuccess, starriver.FailureLevelNormal, 50*time.Millisecond)
+	collector.ObserveTask("demo", "task1", "Template", starriver.TaskStatusSuccess, starriver.FailureLevelNormal, 2*time.Second)
+	collector.ObserveTask(`de"mo`, "task2", "TestNode", starriver.TaskStatusFailure, starriver.FailureLevelError, 0)
+
+	rec := httptest.NewRecorder()
+	collector.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	assert.Equal(t, "text/plain; version=0.0.4; charset=utf-8", rec.Header().Get("Content-Type"))
+	assert.Equal(t, `# HELP starriver_pipeline_runs_total Total number of the pipeline runs.
+# TYPE starriver_pipeline_runs_total counter
+starriver_pipeline_runs_total{pipeline="demo",status="success"} 1
+# HELP starriver_pipeline_duration_seconds Duration of the pipeline runs.
+# TYPE starriver_pipeline_duration_seconds histogram
+starriver_pipeline_duration_seconds_bucket{pipeline="demo",status="success",le="0.1"} 0
+starriver_pipeline_duration_seconds_bucket{pipeline="demo",status="success",le="1"} 1
+starriver_pipeline_duration_seconds_bucket{pipeline="demo",status="success",le="+Inf"} 1
+starriver_pipeline_duration_seconds_sum{pipeline="demo",status="success"} 0.5
+starriver_pipeline_duration_seconds_count{pipeline="demo",status="success"} 1
+# HELP starriver_task_runs_total Total number of the task executions.
+# TYPE starriver_task_runs_total counter
+starriver_task_runs_total{pipeline="de\"mo",task="task2",component="TestNode",status="failure",failure_level="2"} 1
+starriver_task_runs_total{pipeline="demo",task="task1",component="Template",status="success",failure_level="0"} 2
+# HELP starriver_task_duration_seconds Duration of the task executions including retries.
+# TYPE starriver_task_duration_seconds histogram
+starriver_task_duration_seconds_bucket{pipeline="de\"mo",task="task2",component="TestNode",le="0.1"} 1
+starriver_task_duration_seconds_bucket{pipeline="de\"mo",task="task2",component="TestNode",le="1"} 1
+starriver_task_duration_seconds_bucket{pipeline="de\"mo",task="task2",component="TestNode",le="+Inf"} 1
+starriver_task_duration_seconds_sum{pipeline="de\"mo",task="task2",component="TestNode"} 0
+starriver_task_duration_seconds_count{pipeline="de\"mo",task="task2",component="TestNode"} 1
+starriver_task_duration_seconds_bucket{pipeline="demo",task="task1",component="Template",le="0.1"} 1
+starriver_task_duration_seconds_bucket{pipeline="demo",task="task1",component="Template",le="1"} 1
+starriver_task_duration_seconds_bucket{pipeline="demo",task="task1",component="Template",le="+Inf"} 2
+starriver_task_duration_seconds_sum{pipeline="demo",task="task1",component="Template"} 2.05
+starriver_task_duration_seconds_count{pipeline="demo",task="task1",component="Template"} 2
+`, rec.Body.String())
+}
@@ -0,0 +1,238 @@
+package builtin
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/thanksloving/starriver"
+)
+
+const pipelineExt = ".yml"
+
+type (
+	memoryPipelineRepository struct {
+		lock      sync.RWMutex
+		pipelines map[string][]starriver.PipelineDefinition // in the put order
+	}
+
+	// dirPipelineRepository saves each version as a yaml file dir/{name}/{version}.yml, the name and version are path escaped
+	dirPipelineRepository struct {
+		dir  string
+		lock sync.RWMutex
+	}
+)
+
+var (
+	_ starriver.PipelineRepository = (*memoryPipelineRepository)(nil)
+	_ starriver.PipelineRepository = (*dirPipelineRepository)(nil)
+)
+
+// newDefinition checks the name and version, and stamps them on the conf
+func newDefinition(name, version string, conf starriver.PipelineConf) (starriver.PipelineDefinition, error) {
+	if name == "" || name == "." || name == ".." {
+		return starriver.PipelineDefinition{}, fmt.Errorf("invalid pipeline name %q", name)
+	}
+	if version == "" || version == starriver.LatestVersion || version == "." || version == ".." {
+		return starriver.PipelineDefinition{}, fmt.Errorf("invalid pipeline version %q", version)
+	}
+	if conf.Name != "" && conf.Name != name {
+		return starriver.PipelineDefinition{}, fmt.Errorf("pipeline name %q mismatches the conf name %q", name, conf.Name)
+	}
+	conf.Name = name
+	conf.Version = version
+	return starriver.PipelineDefinition{Name: name, Version: version, Conf: conf, CreatedAt: time.Now()}, nil
+}
+
+func isLatest(version string) bool {
+	return version == "" || version == starriver.LatestVersion
+}
+
+func sortDefinitions(defs []starriver.PipelineDefinition) {
+	sort.SliceStable(defs, func(i, j int) bool {
+		if defs[i].Name != defs[j].Name {
+			return defs[i].Name < defs[j].Name
+		}
+		return defs[i].CreatedAt.Before(defs[j].CreatedAt)
+	})
+}
+
+// NewMemoryPipelineRepository returns a pipeline repository in memory
+func NewMemoryPipelineRepository() starriver.PipelineRepository {
+	return &memoryPipelineRepository{pipelines: make(map[string][]starriver.PipelineDefinition)}
+}
+
+func (mr *memoryPipelineRepository) Put(name, version string, conf starriver.PipelineConf) error {
+	def, err := newDefinition(name, version, conf)
+	if err != nil {
+		return err
+	}
+	mr.lock.Lock()
+	defer mr.lock.Unlock()
+	for _, d := range mr.pipelines[name] {
+		if d.Version == version {
+			return fmt.Errorf("pipeline %q version %q exists", name, version)
+		}
+	}
+	mr.pipelines[name] = append(mr.pipelines[name], def)
+	return nil
+}
+
+func (mr *memoryPipelineRepository) Get(name, version string) (*starriver.PipelineDefinition, error) {
+	mr.lock.RLock()
+	defer mr.lock.RUnlock()
+	defs := mr.pipelines[name]
+	if len(defs) == 0 {
+		return nil, nil
+	}
+	if isLatest(version) {
+		def := defs[len(defs)-1]
+		return &def, nil
+	}
+	for _, def := range defs {
+		if def.Version == version {
+			return &def, nil
+		}
+	}
+	return nil, nil
+}
+
+func (mr *memoryPipelineRepository) List() ([]starriver.PipelineDefinition, error) {
+	mr.lock.RLock()
+	defer mr.lock.RUnlock()
+	defs := make([]starriver.PipelineDefinition, 0)
+	for _, versions := range mr.pipelines {
+		defs = append(defs, versions...)
+	}
+	sortDefinitions(defs)
+	return defs, nil
+}
+
+// NewDirPipelineRepository returns a pipeline repository persisted under dir
+func NewDirPipelineRepository(dir string) (starriver.PipelineRepository, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &dirPipelineRepository{dir: dir}, nil
+}
+
+func (dr *dirPipelineRepository) path(name, version string) string {
+	return filepath.Join(dr.dir, url.PathEscape(name), url.PathEscape(version)+pipelineExt)
+}
+
+func (dr *dirPipelineRepository) Put(name, version string, conf starriver.PipelineConf) error {
+	def, err := newDefinition(name, version, conf)
+	if err != nil {
+		return err
+	}
+	content, err := yaml.Marshal(def)
+	if err != nil {
+		return err
+	}
+	dr.lock.Lock()
+	defer dr.lock.Unlock()
+	path := dr.path(name, version)
+	if err = os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+	if os.IsExist(err) {
+		return fmt.Errorf("pipeline %q version %q exists", name, version)
+	}
+	if err != nil {
+		return err
+	}
+	if _, err = f.Write(content); err != nil {
+		_ = f.Close()
+		_ = os.Remove(path)
+		return err
+	}
+	if err = f.Sync(); err != nil {
+		_ = f.Close()
+		_ = os.Remove(path)
+		return err
+	}
+	return f.Close()
+}
+
+func (dr *dirPipelineRepository) load(path string) (*starriver.PipelineDefinition, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var def starriver.PipelineDefinition
+	if err = yaml.Unmarshal(content, &def); err != nil {
+		return nil, fmt.Errorf("invalid pipeline file %q: %v", path, err)
+	}
+	return &def, nil
+}
+
+// versions loads all the versions of the pipeline, sorted by the put time
+func (dr *dirPipelineRepository) versions(escapedName string) ([]starriver.PipelineDefinition, error) {
+	entries, err := os.ReadDir(filepath.Join(dr.dir, escapedName))
+	if err != nil {
+		return nil, err
+	}
+	defs := make([]starriver.PipelineDefinition, 0, len(entries))
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), pipelineExt) {
+			continue
+		}
+		def, err := dr.load(filepath.Join(dr.dir, escapedName, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		defs = append(defs, *def)
+	}
+	sortDefinitions(defs)
+	return defs, nil
+}
+
+func (dr *dirPipelineRepository) Get(name, version string) (*starriver.PipelineDefinition, error) {
+	dr.lock.RLock()
+	defer dr.lock.RUnlock()
+	if !isLatest(version) {
+		def, err := dr.load(dr.path(name, version))
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return def, err
+	}
+	defs, err := dr.versions(url.PathEscape(name))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil || len(defs) == 0 {
+		return nil, err
+	}
+	return &defs[len(defs)-1], nil
+}
+
+func (dr *dirPipelineRepository) List() ([]starriver.PipelineDefinition, error) {
+	dr.lock.RLock()
+	defer dr.lock.RUnlock()
+	entries, err := os.ReadDir(dr.dir)
+	if err != nil {
+		return nil, err
+	}
+	defs := make([]starriver.PipelineDefinition, 0)
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		versions, err := dr.versions(entry.Name())
+		if err != nil {
+			return nil, err
+		}
+		defs = append(defs, versions...)
+	}
+	sortDefinitions(defs)
+	return defs, nil
+}
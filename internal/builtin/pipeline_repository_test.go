@@ -0,0 +1,65 @@
+package builtin
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/thanksloving/starriver"
+)
+
+func TestPipelineRepository(t *testing.T) {
+	dir := t.TempDir()
+	dirRepository, err := NewDirPipelineRepository(dir)
+	assert.NoError(t, err)
+	for _, repository := range []starriver.PipelineRepository{NewMemoryPipelineRepository(), dirRepository} {
+		timeout := time.Minute
+		v1 := starriver.PipelineConf{
+			Timeout:  &timeout,
+			Pipeline: []starriver.Task{{ID: "task1", Name: "TestNode"}},
+		}
+		assert.NoError(t, repository.Put("流程/a", "v1", v1))
+		assert.NoError(t, repository.Put("流程/a", "v2", starriver.PipelineConf{Name: "流程/a"}))
+		assert.NoError(t, repository.Put("b", "v1", starriver.PipelineConf{}))
+		assert.Error(t, repository.Put("流程/a", "v1", v1), "the version can not be overwritten")
+		assert.Error(t, repository.Put("流程/a", starriver.LatestVersion, v1))
+		assert.Error(t, repository.Put("c", "v1", starriver.PipelineConf{Name: "d"}))
+
+		def, err := repository.Get("流程/a", "v1")
+		assert.NoError(t, err)
+		assert.Equal(t, "v1", def.Version)
+		assert.Equal(t, "流程/a", def.Conf.Name)
+		assert.Equal(t, "v1", def.Conf.Version)
+		assert.Equal(t, time.Minute, *def.Conf.Timeout)
+		assert.Equal(t, "TestNode", def.Conf.Pipeline[0].Name)
+
+		for _, version := range []string{"", starriver.LatestVersion} {
+			def, err = repository.Get("流程/a", version)
+			assert.NoError(t, err)
+			assert.Equal(t, "v2", def.Version)
+		}
+
+		def, err = repository.Get("流程/a", "v3")
+		assert.NoError(t, err)
+		assert.Nil(t, def)
+		def, err = repository.Get("c", "")
+		assert.NoError(t, err)
+		assert.Nil(t, def)
+
+		defs, err := repository.List()
+		assert.NoError(t, err)
+		versions := make([]string, 0, len(defs))
+		for _, def := range defs {
+			versions = append(versions, def.Name+"@"+def.Version)
+		}
+		assert.Equal(t, []string{"b@v1", "流程/a@v1", "流程/a@v2"}, versions)
+	}
+
+	// the directory is reopened
+	dirRepository, err = NewDirPipelineRepository(dir)
+	assert.NoError(t, err)
+	def, err := dirRepository.Get("流程/a", "")
+	assert.NoError(t, err)
+	assert.Equal(t, "v2", def.Version)
+}
@@ -0,0 +1,90 @@
+package builtin
+
+import (
+	"container/list"
+	"sync"
+	"time"
+
+	"github.com/thanksloving/starriver"
+)
+
+const defaultResultCacheCapacity = 1024
+
+type (
+	// lruResultCache evicts the least recently used entry when it's full
+	lruResultCache struct {
+		lock     sync.Mutex
+		capacity int
+		entries  map[string]*list.Element
+		order    *list.List // the front is the most recently used
+	}
+
+	cacheEntry struct {
+		key      string
+		data     map[string]interface{}
+		expireAt time.Time // zero means never expires
+	}
+)
+
+var _ starriver.ResultCache = (*lruResultCache)(nil)
+
+// NewLRUResultCache returns an in-memory cache holding at most capacity entries, the default capacity is 1024
+func NewLRUResultCache(capacity int) starriver.ResultCache {
+	if capacity <= 0 {
+		capacity = defaultResultCacheCapacity
+	}
+	return &lruResultCache{
+		capacity: capacity,
+		entries:  make(map[string]*list.Element),
+		order:    list.New(),
+	}
+}
+
+func (c *lruResultCache) Get(key string) (map[string]interface{}, bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	elem, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	entry := elem.Value.(*cacheEntry)
+	if !entry.expireAt.IsZero() && time.Now().After(entry.expireAt) {
+		c.order.Remove(elem)
+		delete(c.entries, key)
+		return nil, false
+	}
+	c.order.MoveToFront(elem)
+	return copyData(entry.data), true
+}
+
+func (c *lruResultCache) Set(key string, data map[string]interface{}, ttl time.Duration) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	entry := &cacheEntry{key: key, data: copyData(data)}
+	if ttl > 0 {
+		entry.expireAt = time.Now().Add(ttl)
+	}
+	if elem, ok := c.entries[key]; ok {
+		elem.Value = entry
+		c.order.MoveToFront(elem)
+		return
+	}
+	c.entries[key] = c.order.PushFront(entry)
+	for c.order.Len() > c.capacity {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*cacheEntry).key)
+	}
+}
+
+// copyData copies the outputs, so the cached entry is not changed by the runs
+func copyData(data map[string]interface{}) map[string]interface{} {
+	if data == nil {
+		return nil
+	}
+	copied := make(map[string]interface{}, len(data))
+	for key, val := range data {
+		copied[key] = val
+	}
+	return copied
+}
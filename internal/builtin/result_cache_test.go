@@ -0,0 +1,34 @@
+package builtin
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLRUResultCache(t *testing.T) {
+	cache := NewLRUResultCache(2)
+	cache.Set("a", map[string]interface{}{"out": "a"}, 0)
+	cache.Set("b", map[string]interface{}{"out": "b"}, 0)
+
+	data, ok := cache.Get("a")
+	assert.True(t, ok)
+	assert.Equal(t, map[string]interface{}{"out": "a"}, data)
+	data["out"] = "changed"
+
+	// b is the least recently used
+	cache.Set("c", map[string]interface{}{"out": "c"}, 0)
+	_, ok = cache.Get("b")
+	assert.False(t, ok)
+	data, ok = cache.Get("a")
+	assert.True(t, ok)
+	assert.Equal(t, map[string]interface{}{"out": "a"}, data)
+	_, ok = cache.Get("c")
+	assert.True(t, ok)
+
+	cache.Set("d", map[string]interface{}{"out": "d"}, time.Millisecond)
+	time.Sleep(5 * time.Millisecond)
+	_, ok = cache.Get("d")
+	assert.False(t, ok)
+}
@@ -3,15 +3,19 @@ package core
 import (
 	"fmt"
 	"strings"
+	"text/template"
 
 	"github.com/sirupsen/logrus"
 
 	"github.com/thanksloving/starriver"
 	"github.com/thanksloving/starriver/internal/dag"
+	"github.com/thanksloving/starriver/internal/expr"
 	"github.com/thanksloving/starriver/internal/util"
 	"github.com/thanksloving/starriver/registry"
 )
 
+const switchNodeName = starriver.BuiltinNodePrefix + string(dag.NodeTypeSwitch)
+
 func BuildPipeline(pc starriver.PipelineConf, status starriver.PipelineStatus, taskStatuses map[string]starriver.TaskStatus) (_ starriver.Pipeline, err error) {
 	defer func() {
 		if r := recover(); r != nil {
@@ -22,18 +26,45 @@ func BuildPipeline(pc starriver.PipelineConf, status starriver.PipelineStatus, t
 	pipeline := &pipeline{
 		env:          pc.Env,
 		Name:         pc.Name,
+		conf:         pc,
 		status:       status,
 		ResultKeys:   pc.Result,
 		Timeout:      pc.Timeout,
 		TaskStatuses: taskStatuses,
 	}
 	tc := make(map[string]starriver.TaskConfigure)
+	compensations := make(map[string]sideTask)
+	cacheKeys := make(map[string]*template.Template)
+	cacheNames := make(map[string]string)
+	triggerRules := make(map[string]dag.TriggerRule)
 	nodes := make(map[string]dag.Vertex)
 	graph := dag.Graph{}
+	branches := make(map[string][]string)
+	for _, task := range pc.Pipeline {
+		for _, depend := range task.Depends {
+			if depend.Branch != "" {
+				branches[depend.ID] = append(branches[depend.ID], depend.Branch)
+			}
+		}
+	}
 	for _, task := range pc.Pipeline {
 		tc[task.ID] = task.Config
+		if task.TriggerRule != "" {
+			if strings.HasPrefix(task.Name, starriver.BuiltinNodePrefix) {
+				return nil, fmt.Errorf("task %q: builtin node %q does not support trigger rule", task.ID, task.Name)
+			}
+			rule, err := dag.ParseTriggerRule(task.TriggerRule)
+			if err != nil {
+				return nil, fmt.Errorf("task %q: %v", task.ID, err)
+			}
+			triggerRules[task.ID] = rule
+		}
 		var node starriver.Node
-		if strings.HasPrefix(task.Name, starriver.BuiltinNodePrefix) {
+		if task.Name == switchNodeName {
+			if node, err = newSwitchNode(task, branches[task.ID]); err != nil {
+				return nil, err
+			}
+		} else if strings.HasPrefix(task.Name, starriver.BuiltinNodePrefix) {
 			node = registry.NewBuiltinNode(task.ID, task.Name)
 		} else {
 			component := registry.GetComponent(task.Name, task.Namespace)
@@ -41,8 +72,28 @@ func BuildPipeline(pc starriver.PipelineConf, status starriver.PipelineStatus, t
 				return nil, fmt.Errorf("can not found node with name %q", task.Name)
 			}
 			node = component.Executor(task.ID)
-			if tc[task.ID].Timeout == nil && component.Timeout != nil {
-				*tc[task.ID].Timeout = *component.Timeout
+			if cache := task.Config.Cache; cache != nil {
+				if _, ok := node.(starriver.WithParameters); !ok && cache.Key == "" {
+					return nil, fmt.Errorf("task %q: cache key is required, component %q has no parameters", task.ID, task.Name)
+				}
+				cacheNames[task.ID] = task.Name
+				if task.Namespace != nil {
+					cacheNames[task.ID] = *task.Namespace + "." + task.Name
+				}
+			}
+			if config := tc[task.ID]; config.Timeout == nil && component.Timeout != nil {
+				config.Timeout = component.Timeout
+				tc[task.ID] = config
+			}
+		}
+		if task.Compensate != nil {
+			if compensations[task.ID], err = newCompensation(task); err != nil {
+				return nil, err
+			}
+		}
+		if cache := task.Config.Cache; cache != nil && cache.Key != "" {
+			if cacheKeys[task.ID], err = template.New(task.ID).Parse(cache.Key); err != nil {
+				return nil, fmt.Errorf("task %q: cache key %v", task.ID, err)
 			}
 		}
 		nodes[node.ID()] = node
@@ -52,11 +103,32 @@ func BuildPipeline(pc starriver.PipelineConf, status starriver.PipelineStatus, t
 		}
 	}
 	pipeline.TaskConfigures = tc
+	pipeline.compensations = compensations
+	if pipeline.onSuccess, err = newHookTasks("on_success", pc.OnSuccess); err != nil {
+		return nil, err
+	}
+	if pipeline.onFailure, err = newHookTasks("on_failure", pc.OnFailure); err != nil {
+		return nil, err
+	}
+	if pipeline.finally, err = newHookTasks("finally", pc.Finally); err != nil {
+		return nil, err
+	}
 	for _, task := range pc.Pipeline {
 		target := nodes[task.ID]
 		for _, depend := range task.Depends {
 			source := nodes[depend.ID]
-			if depend.Condition != nil {
+			if depend.Branch != "" {
+				if _, ok := source.(dag.SwitchNode); !ok {
+					return nil, fmt.Errorf("task %q depends on %q, branch is only for the %s node", task.ID, depend.ID, switchNodeName)
+				}
+				graph.Connect(dag.BranchEdge(source, target, depend.Branch).WithProperties(depend.Properties))
+			} else if depend.Condition != nil && depend.Condition.Expr != "" {
+				expression, err := expr.Compile(depend.Condition.Expr)
+				if err != nil {
+					return nil, fmt.Errorf("task %q depends on %q, invalid condition expr %q: %v", task.ID, depend.ID, depend.Condition.Expr, err)
+				}
+				graph.Connect(dag.ExpressionEdge(source, target, expression).WithProperties(depend.Properties))
+			} else if depend.Condition != nil {
 				graph.Connect(dag.
 					ConditionEdge(source, target,
 						depend.Condition.Key, depend.Condition.Value, depend.Condition.Operator).
@@ -66,7 +138,7 @@ func BuildPipeline(pc starriver.PipelineConf, status starriver.PipelineStatus, t
 			}
 		}
 	}
-	acyclicGraph := dag.NewDAG(graph)
+	acyclicGraph := dag.NewDAG(graph, dag.WithTriggerRules(triggerRules))
 	if err := acyclicGraph.Validate(); err != nil {
 		return nil, err
 	}
@@ -78,6 +150,35 @@ func BuildPipeline(pc starriver.PipelineConf, status starriver.PipelineStatus, t
 	pipeline.walker = GraphWalker{
 		ParallelSem: util.NewSemaphore(sem),
 		Pipeline:    pipeline,
+		cacheKeys:   cacheKeys,
+		cacheNames:  cacheNames,
 	}
 	return pipeline, nil
 }
+
+// newSwitchNode builds the @switch node, branches are the branch names of the downstream tasks
+func newSwitchNode(task starriver.Task, branches []string) (dag.SwitchNode, error) {
+	if task.Switch == nil || (task.Switch.Key == "" && task.Switch.Expr == "") {
+		return nil, fmt.Errorf("task %q: %s node requires the switch key or expr", task.ID, switchNodeName)
+	}
+	var expression *expr.Expression
+	if task.Switch.Expr != "" {
+		var err error
+		if expression, err = expr.Compile(task.Switch.Expr); err != nil {
+			return nil, fmt.Errorf("task %q: invalid switch expr %q: %v", task.ID, task.Switch.Expr, err)
+		}
+	}
+	return dag.NewSwitchNode(task.ID, task.Switch.Key, expression, branches), nil
+}
+
+// newCompensation builds the compensation of the task, the executable has the same id as the task
+func newCompensation(task starriver.Task) (sideTask, error) {
+	if strings.HasPrefix(task.Name, starriver.BuiltinNodePrefix) {
+		return sideTask{}, fmt.Errorf("task %q: builtin node %q does not support compensate", task.ID, task.Name)
+	}
+	compensation, err := newSideTask(task.ID, task.Compensate.Name, task.Compensate.Namespace, task.Compensate.Config)
+	if err != nil {
+		return sideTask{}, fmt.Errorf("task %q compensate: %v", task.ID, err)
+	}
+	return compensation, nil
+}
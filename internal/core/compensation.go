@@ -0,0 +1,37 @@
+package core
+
+import (
+	"context"
+
+	"github.com/thanksloving/starriver"
+)
+
+// compensate runs the compensations of the succeeded tasks one by one in the reverse topological order,
+// so a task is undone after the tasks depending on it.
+func (p *pipeline) compensate(dataContext starriver.DataContext) []starriver.CompensationRecord {
+	if len(p.compensations) == 0 {
+		return nil
+	}
+	// the run may fail because it's stopped or timeout, the compensations still have to run
+	ctx := context.WithoutCancel(dataContext.Context())
+	var records []starriver.CompensationRecord
+	for _, vertex := range p.Graph.ReverseTopologicalOrder() {
+		c, ok := p.compensations[vertex.ID()]
+		if !ok || p.GetTaskStatus(vertex.ID()) != starriver.TaskStatusSuccess {
+			continue
+		}
+		// the compensation finds the outputs of the compensated task first
+		record := c.run(ctx, dataContext, "compensate "+c.id, func(key string) (interface{}, bool) {
+			return dataContext.GetDependNodeValue(c.id, key)
+		})
+		records = append(records, starriver.CompensationRecord{
+			TaskID:    record.TaskID,
+			Component: c.name,
+			Status:    record.Status,
+			Error:     record.Error,
+			StartedAt: record.StartedAt,
+			EndedAt:   record.EndedAt,
+		})
+	}
+	return records
+}
@@ -3,6 +3,7 @@ package core
 import (
 	"context"
 	"fmt"
+	"io"
 	"sync"
 	"time"
 
@@ -58,6 +59,8 @@ func NewDataContext(ctx context.Context, pipeline starriver.Pipeline, initialDat
 	if sc.SharedDataStore == nil {
 		sc.SharedDataStore = builtin.NewSharedDataStore()
 	}
+	// a persistent data store locates and recovers the data of the run by the raw request id
+	sc.SharedDataStore.Configure(pipeline.GetName(), sc.requestID)
 	sc.pipeline = pipeline
 	sc.requestID = fmt.Sprintf("%s#%s", pipeline.GetName(), sc.requestID)
 	for k, v := range initialData {
@@ -92,6 +95,12 @@ func SetLogger(logger starriver.Logger) ContextOption {
 
 func (dc *dataContext) Release() {
 	dc.cancel()
+	if closer, ok := dc.SharedDataStore.(io.Closer); ok {
+		// e.g. the file of the persistent data store
+		if err := closer.Close(); err != nil {
+			dc.Errorf("close shared data store error %v", err)
+		}
+	}
 	dc.ctx = nil
 	dc.cancel = nil
 	dc.requestID = ""
@@ -146,3 +155,15 @@ func (dc *dataContext) WithTimeout(timeout time.Duration) context.CancelFunc {
 	dc.ctx, cancel = context.WithTimeout(dc.ctx, timeout)
 	return cancel
 }
+
+// WithCancel makes the run of the created data context cancelable, the cancel function can be called at any time,
+// even after the data context is released.
+func WithCancel(sc starriver.DataContext) context.CancelFunc {
+	dc, ok := sc.(*dataContext)
+	if !ok {
+		return sc.Stop
+	}
+	var cancel context.CancelFunc
+	dc.ctx, cancel = context.WithCancel(dc.ctx)
+	return cancel
+}
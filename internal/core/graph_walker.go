@@ -3,6 +3,8 @@ package core
 import (
 	"fmt"
 	"sync"
+	"text/template"
+	"time"
 
 	"github.com/hashicorp/go-multierror"
 
@@ -16,7 +18,17 @@ type GraphWalker struct {
 	ParallelSem util.Semaphore
 	lock        sync.Locker
 	serial      bool // execute the pipeline by serial, default is false
+	dryRun      bool // prepare the parameters without executing the components, for the plan
 	Pipeline    starriver.Pipeline
+	cacheKeys   map[string]*template.Template // the parsed key templates of the cache policies
+	cacheNames  map[string]string             // the components of the cached tasks, prefixed by the namespaces
+	inflight    sync.WaitGroup                // the executing callbacks, they are left running when the data context is done
+
+	recordLock sync.Mutex
+	attempts   map[string]int
+	records    []starriver.TaskRecord
+	missing    map[string][]string
+	cacheHits  map[string]bool
 }
 
 func (walker *GraphWalker) callback(dataContext starriver.DataContext, vertex dag.Vertex) (resp starriver.Response) {
@@ -35,12 +47,15 @@ func (walker *GraphWalker) callback(dataContext starriver.DataContext, vertex da
 		if resp == nil {
 			resp = helper.NewErrorResponse(fmt.Errorf("%q response is nil, unkonwn exeception", vertex.ID()))
 		}
+		fireTaskHooks(dataContext, vertex.ID(), resp)
 	}()
 	if walker.serial {
 		walker.lock.Lock()
 		defer walker.lock.Unlock()
 	}
+	walker.inflight.Add(1)
 	go func() {
+		defer walker.inflight.Done()
 		var localResp starriver.Response
 		defer func() {
 			if r := recover(); r != nil {
@@ -50,6 +65,14 @@ func (walker *GraphWalker) callback(dataContext starriver.DataContext, vertex da
 		}()
 		if executable, ok := vertex.(starriver.Executable); ok {
 			localResp = walker.execute(dataContext, executable)
+		} else if sn, ok := vertex.(dag.SwitchNode); ok {
+			branch, err := sn.Select(dataContext)
+			if err != nil {
+				localResp = helper.NewErrorResponse(err)
+			} else {
+				dataContext.Debugf("SwitchNode %q selects branch %q", vertex.ID(), branch)
+				localResp = helper.NewSuccessDataResponse(map[string]interface{}{dag.SwitchBranchKey: branch})
+			}
 		} else if _, ok := vertex.(dag.Node); ok {
 			dataContext.Debugf("AnyNode Pass, %q", vertex.ID())
 			localResp = helper.NewSuccessResponse()
@@ -66,7 +89,12 @@ func (walker *GraphWalker) callback(dataContext starriver.DataContext, vertex da
 }
 
 func (walker *GraphWalker) Walk(graph dag.DAG, dataContext starriver.DataContext) error {
-	responses := graph.Walk(dataContext, walker.callback)
+	responses, records := graph.Walk(dataContext, walker.callback)
+	// the cancelled tasks may be still executing, wait for them before the data context is used by the hooks or released
+	walker.inflight.Wait()
+	walker.recordLock.Lock()
+	walker.records = records
+	walker.recordLock.Unlock()
 	var errs *multierror.Error
 	for _, resp := range responses {
 		if resp.GetFailureLevel() > starriver.FailureLevelWarning && !resp.IsPass() {
@@ -80,19 +108,30 @@ func (walker *GraphWalker) Walk(graph dag.DAG, dataContext starriver.DataContext
 
 func (walker *GraphWalker) execute(dataContext starriver.DataContext, executable starriver.Executable) (resp starriver.Response) {
 	tc := walker.Pipeline.GetTaskConfigure(executable.ID())
-	if tc.SkipExecution {
-		dataContext.Pipeline().SetTaskStatus(executable.ID(), starriver.TaskStatusSkipped)
-		return helper.NewSuccessResponse()
+	if walker.dryRun {
+		return walker.simulate(dataContext, executable, tc)
 	}
+	metrics := starriver.MetricsCollectorFromContext(dataContext.Context())
+	start := time.Now()
 	walker.ParallelSem.Acquire()
+	metrics.ObserveSemaphoreWait(walker.Pipeline.GetName(), starriver.SemaphorePipeline, time.Since(start))
+	start = time.Now()
+	attemptContext := newAttemptDataContext(dataContext)
+	var cacheHit bool // the component is not executed, so its callbacks are not called either
 	defer func() {
+		walker.recordAttempts(executable.ID(), attemptContext.attempts)
+		starriver.SpanFromContext(dataContext.Context()).SetAttributes(starriver.Attr(starriver.AttrAttempts, attemptContext.attempts))
 		if r := recover(); r != nil {
 			if resp == nil {
 				resp = helper.NewErrorResponse(fmt.Errorf("%q executable execute panic, %v", executable.ID(), r))
 			}
 			dataContext.Errorf("component %q execute error, err=%v", executable.ID(), r)
-		} else if ae, ok := executable.(starriver.AfterExecute); ok {
-			ae.After(dataContext, resp)
+		} else if ae, ok := executable.(starriver.AfterExecute); ok && !cacheHit {
+			ae.After(attemptContext, resp)
+		}
+		if resp != nil {
+			metrics.ObserveTask(walker.Pipeline.GetName(), executable.ID(), walker.component(executable.ID()),
+				resp.GetStatus(), resp.GetFailureLevel(), time.Since(start))
 		}
 		walker.ParallelSem.Release()
 	}()
@@ -101,26 +140,127 @@ func (walker *GraphWalker) execute(dataContext starriver.DataContext, executable
 		var err error
 		ap := &assembleParam{id: executable.ID()}
 		if param, err = ap.prepareParameter(dataContext, tc.Params, p.ParameterNew()); err != nil {
+			starriver.SpanFromContext(dataContext.Context()).SetAttributes(starriver.Attr(starriver.AttrParamError, err.Error()))
 			return helper.NewErrorResponse(err)
 		}
 	}
+	var cacheKey string
+	cache := starriver.ResultCacheFromContext(dataContext.Context())
+	if tc.Cache != nil && cache != nil {
+		var err error
+		if cacheKey, err = walker.cacheKey(dataContext, executable.ID(), tc.Cache, param); err != nil {
+			dataContext.Warnf("component %q cache key error, the result is not cached, err=%v", executable.ID(), err)
+		} else if data, ok := cache.Get(cacheKey); ok {
+			dataContext.Debugf("component %q cache hit, key=%s", executable.ID(), cacheKey)
+			walker.recordCacheHit(executable.ID())
+			cacheHit = true
+			return helper.NewSuccessDataResponse(data)
+		}
+	}
 	if be, ok := executable.(starriver.BeforeExecute); ok {
 		be.Before(dataContext)
 	}
-	resp = executable.Execute(dataContext, param)
+	rp := retryPolicy{tc.Retry}
+	resp = executable.Execute(attemptContext, param)
+	for rp.retryable(resp, attemptContext.attempts) {
+		dataContext.Warnf("component %q attempt %d failed, err=%v", executable.ID(), attemptContext.attempts, resp.GetError())
+		if !rp.wait(dataContext, attemptContext.attempts) {
+			break
+		}
+		attemptContext.next()
+		resp = executable.Execute(attemptContext, param)
+	}
 	if tc.AbortIfError && resp.GetFailureLevel() > starriver.FailureLevelWarning {
 		resp.SetFailureLevel(starriver.FailureLevelFatal)
 	}
 	if tc.AlwaysPass && !resp.IsPass() {
 		resp.SetPass(true)
 	}
+	if cacheKey != "" && resp.GetStatus() == starriver.TaskStatusSuccess && resp.GetFailureLevel() == starriver.FailureLevelNormal {
+		var ttl time.Duration
+		if tc.Cache.TTL != nil {
+			ttl = *tc.Cache.TTL
+		}
+		cache.Set(cacheKey, resp.GetData(), ttl)
+	}
 	dataContext.Debugf("component %q execute done, resp=%+v", executable.ID(), resp)
 	if listener, ok := executable.(starriver.Listener); ok {
 		if resp.GetFailureLevel() == starriver.FailureLevelNormal {
-			listener.OnSuccess(dataContext, resp.GetData())
+			listener.OnSuccess(attemptContext, resp.GetData())
 		} else {
-			listener.OnFailure(dataContext, resp.GetError())
+			listener.OnFailure(attemptContext, resp.GetError())
 		}
 	}
 	return resp
 }
+
+// simulate prepares the parameter and returns the fake outputs of the Simulator instead of executing the component
+func (walker *GraphWalker) simulate(dataContext starriver.DataContext, executable starriver.Executable, tc starriver.TaskConfigure) starriver.Response {
+	var param interface{}
+	if p, ok := executable.(starriver.WithParameters); ok {
+		var err error
+		ap := &assembleParam{id: executable.ID(), dryRun: true}
+		param, err = ap.prepareParameter(dataContext, tc.Params, p.ParameterNew())
+		if len(ap.missing) > 0 {
+			walker.recordLock.Lock()
+			if walker.missing == nil {
+				walker.missing = make(map[string][]string)
+			}
+			walker.missing[executable.ID()] = ap.missing
+			walker.recordLock.Unlock()
+		}
+		if err != nil {
+			return helper.NewErrorResponse(err)
+		}
+	}
+	if simulator, ok := executable.(starriver.Simulator); ok {
+		return helper.NewSuccessDataResponse(simulator.Simulate(dataContext, param))
+	}
+	return helper.NewSuccessResponse()
+}
+
+func (walker *GraphWalker) recordAttempts(taskID string, attempts int) {
+	walker.recordLock.Lock()
+	defer walker.recordLock.Unlock()
+	if walker.attempts == nil {
+		walker.attempts = make(map[string]int)
+	}
+	walker.attempts[taskID] = attempts
+}
+
+// Report returns the timeline of the last walk, the tasks not walked are appended with the status only
+func (walker *GraphWalker) Report(startedAt time.Time) *starriver.Report {
+	attempts := walker.Attempts()
+	walker.recordLock.Lock()
+	records := append([]starriver.TaskRecord(nil), walker.records...)
+	for idx := range records {
+		records[idx].CacheHit = walker.cacheHits[records[idx].TaskID]
+	}
+	walker.recordLock.Unlock()
+	walked := make(map[string]struct{}, len(records))
+	for idx := range records {
+		walked[records[idx].TaskID] = struct{}{}
+		records[idx].Attempts = attempts[records[idx].TaskID]
+	}
+	for _, task := range walker.conf().Pipeline {
+		if _, ok := walked[task.ID]; !ok {
+			records = append(records, starriver.TaskRecord{TaskID: task.ID, Status: walker.Pipeline.GetTaskStatus(task.ID)})
+		}
+	}
+	return &starriver.Report{
+		StartedAt: startedAt,
+		EndedAt:   time.Now(),
+		Tasks:     records,
+	}
+}
+
+// Attempts returns how many times each executed task has been attempted
+func (walker *GraphWalker) Attempts() map[string]int {
+	walker.recordLock.Lock()
+	defer walker.recordLock.Unlock()
+	attempts := make(map[string]int, len(walker.attempts))
+	for taskID, n := range walker.attempts {
+		attempts[taskID] = n
+	}
+	return attempts
+}
@@ -35,14 +35,15 @@ func (m *mockExecutable) Execute(dataContext starriver.DataContext, param interf
 }
 
 type mockPipeline struct {
-	status       starriver.PipelineStatus
-	taskStatuses map[string]starriver.TaskStatus
-	lock         sync.Mutex
+	status         starriver.PipelineStatus
+	taskStatuses   map[string]starriver.TaskStatus
+	taskConfigures map[string]starriver.TaskConfigure
+	lock           sync.Mutex
 }
 
 func (p *mockPipeline) GetName() string { return "mock_pipeline" }
 func (p *mockPipeline) GetTaskConfigure(taskId string) starriver.TaskConfigure {
-	return starriver.TaskConfigure{}
+	return p.taskConfigures[taskId]
 }
 func (p *mockPipeline) Run(dataContext starriver.DataContext) starriver.Result {
 	return starriver.Result{}
@@ -94,8 +95,97 @@ func TestGraphWalker_Callback_ContextDone(t *testing.T) {
 		assert.NotNil(t, resp)
 		assert.False(t, resp.IsPass())
 		assert.ErrorIs(t, resp.GetError(), context.Canceled)
-		
+
 		// Wait a bit to ensure the goroutine inside callback finishes and doesn't panic on closed channel
 		time.Sleep(200 * time.Millisecond)
 	})
 }
+
+type flakyExecutable struct {
+	id       string
+	failures int
+	attempts []int
+}
+
+func (f *flakyExecutable) ID() string {
+	return f.id
+}
+
+func (f *flakyExecutable) Execute(dataContext starriver.DataContext, param interface{}) starriver.Response {
+	f.attempts = append(f.attempts, starriver.GetAttempts(dataContext))
+	if len(f.attempts) <= f.failures {
+		return helper.NewErrorResponse(fmt.Errorf("flaky error"))
+	}
+	return helper.NewSuccessResponse()
+}
+
+func TestGraphWalker_Execute_Retry(t *testing.T) {
+	interval := time.Millisecond
+	newWalker := func(retry *starriver.RetryPolicy) (*GraphWalker, starriver.DataContext) {
+		p := &mockPipeline{
+			taskStatuses: map[string]starriver.TaskStatus{"task1": starriver.TaskStatusInit},
+			taskConfigures: map[string]starriver.TaskConfigure{
+				"task1": {Retry: retry},
+			},
+		}
+		walker := &GraphWalker{
+			ParallelSem: util.NewSemaphore(10),
+			Pipeline:    p,
+		}
+		return walker, NewDataContext(context.Background(), p, nil)
+	}
+
+	t.Run("succeed after retries", func(t *testing.T) {
+		walker, dc := newWalker(&starriver.RetryPolicy{MaxAttempts: 3, Interval: &interval, Backoff: starriver.BackoffExponential})
+		exec := &flakyExecutable{id: "task1", failures: 2}
+		resp := walker.execute(dc, exec)
+		assert.True(t, resp.IsPass())
+		assert.Equal(t, []int{1, 2, 3}, exec.attempts)
+		assert.Equal(t, 3, walker.Attempts()["task1"])
+	})
+
+	t.Run("exhausted", func(t *testing.T) {
+		walker, dc := newWalker(&starriver.RetryPolicy{MaxAttempts: 2, Interval: &interval})
+		exec := &flakyExecutable{id: "task1", failures: 5}
+		resp := walker.execute(dc, exec)
+		assert.False(t, resp.IsPass())
+		assert.Equal(t, 2, walker.Attempts()["task1"])
+	})
+
+	t.Run("failure level not retryable", func(t *testing.T) {
+		walker, dc := newWalker(&starriver.RetryPolicy{MaxAttempts: 3, Interval: &interval, RetryOn: []starriver.FailureLevel{starriver.FailureLevelFatal}})
+		exec := &flakyExecutable{id: "task1", failures: 1}
+		resp := walker.execute(dc, exec)
+		assert.False(t, resp.IsPass())
+		assert.Equal(t, 1, walker.Attempts()["task1"])
+	})
+
+	t.Run("stop waiting when context done", func(t *testing.T) {
+		wait := time.Hour
+		walker, dc := newWalker(&starriver.RetryPolicy{MaxAttempts: 3, Interval: &wait})
+		exec := &flakyExecutable{id: "task1", failures: 5}
+		time.AfterFunc(50*time.Millisecond, dc.Stop)
+		resp := walker.execute(dc, exec)
+		assert.False(t, resp.IsPass())
+		assert.Equal(t, 1, walker.Attempts()["task1"])
+	})
+}
+
+func TestRetryPolicy_Backoff(t *testing.T) {
+	interval, maxInterval := 10*time.Millisecond, 30*time.Millisecond
+	rp := retryPolicy{&starriver.RetryPolicy{Backoff: starriver.BackoffExponential, Interval: &interval, MaxInterval: &maxInterval}}
+	assert.Equal(t, 10*time.Millisecond, rp.backoff(1))
+	assert.Equal(t, 20*time.Millisecond, rp.backoff(2))
+	assert.Equal(t, 30*time.Millisecond, rp.backoff(3))
+
+	rp.Jitter = 0.5
+	for i := 0; i < 10; i++ {
+		d := rp.backoff(1)
+		assert.True(t, d >= 5*time.Millisecond && d <= 15*time.Millisecond)
+	}
+
+	// the wait overflows time.Duration without the max interval
+	rp = retryPolicy{&starriver.RetryPolicy{Backoff: starriver.BackoffExponential, Interval: &interval}}
+	assert.Equal(t, defaultMaxRetryInterval, rp.backoff(100))
+	assert.Equal(t, defaultMaxRetryInterval, rp.backoff(2000))
+}
@@ -0,0 +1,32 @@
+package core
+
+import (
+	"github.com/thanksloving/starriver"
+)
+
+type (
+	// TaskHook is called after a task is executed, with the response which decides the status of the task
+	TaskHook func(dataContext starriver.DataContext, taskID string, resp starriver.Response)
+
+	taskHooksKey struct{}
+)
+
+// AddTaskHook installs the hook for the run of the data context, it must be called before the run.
+func AddTaskHook(dataContext starriver.DataContext, hook TaskHook) {
+	hooks, _ := dataContext.Value(taskHooksKey{}).([]TaskHook)
+	dataContext.WithValue(taskHooksKey{}, append(hooks[:len(hooks):len(hooks)], hook))
+}
+
+func fireTaskHooks(dataContext starriver.DataContext, taskID string, resp starriver.Response) {
+	hooks, _ := dataContext.Value(taskHooksKey{}).([]TaskHook)
+	for _, hook := range hooks {
+		hook(dataContext, taskID, resp)
+	}
+}
+
+// WithTaskEventHandler installs the handler into the created data context, it must be called before the run.
+func WithTaskEventHandler(sc starriver.DataContext, handler starriver.TaskEventHandler) {
+	if dc, ok := sc.(*dataContext); ok {
+		dc.ctx = starriver.ContextWithTaskEventHandler(dc.ctx, handler)
+	}
+}
@@ -0,0 +1,28 @@
+package core
+
+import (
+	"github.com/thanksloving/starriver"
+)
+
+// WithMetricsCollector installs the collector into the created data context, it must be called before the run.
+func WithMetricsCollector(sc starriver.DataContext, collector starriver.MetricsCollector) {
+	if dc, ok := sc.(*dataContext); ok {
+		dc.ctx = starriver.ContextWithMetricsCollector(dc.ctx, collector)
+	}
+}
+
+// component returns the component name of the task
+// conf returns the configure of the walked pipeline, it's empty for the other implementations of the pipeline
+func (walker *GraphWalker) conf() starriver.PipelineConf {
+	conf, _ := PipelineConf(walker.Pipeline)
+	return conf
+}
+
+func (walker *GraphWalker) component(taskID string) string {
+	for _, task := range walker.conf().Pipeline {
+		if task.ID == taskID {
+			return task.Name
+		}
+	}
+	return ""
+}
@@ -2,6 +2,7 @@ package core
 
 import (
 	"fmt"
+	"math"
 	"reflect"
 
 	dafaults "github.com/mcuadros/go-defaults"
@@ -10,7 +11,9 @@ import (
 )
 
 type assembleParam struct {
-	id string
+	id      string
+	dryRun  bool     // record the missing required variables instead of failing, for the plan
+	missing []string // the missing required variables recorded by the dry run
 }
 
 func (ap *assembleParam) prepareParameter(dataContext starriver.DataContext, paramConfigs starriver.Params, paramObj interface{}) (param interface{}, err error) {
@@ -33,7 +36,7 @@ func (ap *assembleParam) prepareParameter(dataContext starriver.DataContext, par
 			continue
 		}
 		if field := v.FieldByName(paramConfig.Name); field.CanAddr() {
-			field.Set(reflect.ValueOf(val))
+			field.Set(convertNumber(reflect.ValueOf(val), field.Type()))
 		} else {
 			err = fmt.Errorf("[PrepareParameter]id= %q parameter %q init failed, config=%+v", ap.id, paramConfig.Name, paramConfig)
 			return nil, err
@@ -46,7 +49,9 @@ func (ap *assembleParam) getValue(dataContext starriver.DataContext, paramConfig
 	switch paramConfig.Type {
 	case starriver.ParamTypeVariable:
 		var ok bool
-		if val, ok = dataContext.Get(paramConfig.Variable); !ok && paramConfig.Required {
+		if val, ok = dataContext.Get(paramConfig.Variable); !ok && paramConfig.Required && ap.dryRun {
+			ap.missing = append(ap.missing, paramConfig.Variable)
+		} else if !ok && paramConfig.Required {
 			err = fmt.Errorf("[PrepareParameter]id=%q get %q failed", ap.id, paramConfig.Variable)
 		}
 	case starriver.ParamTypeLiteral:
@@ -76,3 +81,34 @@ func (ap *assembleParam) getValue(dataContext starriver.DataContext, paramConfig
 	}
 	return val, err
 }
+
+// convertNumber converts the number to the numeric field, the numbers restored from the JSON snapshot or journal are
+// float64, they are converted to the integer field only if they are integral and not overflowed
+func convertNumber(val reflect.Value, typ reflect.Type) reflect.Value {
+	if val.Type().AssignableTo(typ) {
+		return val
+	}
+	switch val.Kind() {
+	case reflect.Float32, reflect.Float64:
+		f := val.Float()
+		if f != math.Trunc(f) {
+			return val
+		}
+		switch typ.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			if f >= math.MinInt64 && f < math.MaxInt64 && !reflect.Zero(typ).OverflowInt(int64(f)) {
+				return val.Convert(typ)
+			}
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			if f >= 0 && f < math.MaxUint64 && !reflect.Zero(typ).OverflowUint(uint64(f)) {
+				return val.Convert(typ)
+			}
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if typ.Kind() == reflect.Float32 || typ.Kind() == reflect.Float64 {
+			return val.Convert(typ)
+		}
+	}
+	return val
+}
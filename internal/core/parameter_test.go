@@ -39,8 +39,21 @@ func TestPrepareParameter_NilValue(t *testing.T) {
 	res, err := ap.prepareParameter(dc, params, obj)
 	assert.NoError(t, err)
 	assert.NotNil(t, res)
-	
+
 	resObj := res.(*dummyParam)
 	assert.Equal(t, "", resObj.Name)
 	assert.Equal(t, 18, resObj.Age)
 }
+
+func TestPrepareParameter_ConvertNumber(t *testing.T) {
+	dc := NewDataContext(context.Background(), &mockPipeline{}, map[string]interface{}{"age": float64(20), "height": 1.8})
+	ap := &assembleParam{id: "test"}
+
+	res, err := ap.prepareParameter(dc, starriver.Params{{Name: "Age", Type: starriver.ParamTypeVariable, Variable: "age"}}, &dummyParam{})
+	assert.NoError(t, err)
+	assert.Equal(t, 20, res.(*dummyParam).Age)
+
+	// the fraction is not dropped
+	_, err = ap.prepareParameter(dc, starriver.Params{{Name: "Age", Type: starriver.ParamTypeVariable, Variable: "height"}}, &dummyParam{})
+	assert.Error(t, err)
+}
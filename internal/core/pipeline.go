@@ -1,6 +1,7 @@
 package core
 
 import (
+	"sort"
 	"sync"
 	"time"
 
@@ -15,6 +16,7 @@ var (
 type (
 	pipeline struct {
 		Name           string
+		conf           starriver.PipelineConf
 		env            map[string]interface{}
 		status         starriver.PipelineStatus
 		walker         GraphWalker
@@ -24,6 +26,11 @@ type (
 		ResultKeys     []string
 		Timeout        *time.Duration
 		Graph          dag.DAG
+		compensations  map[string]sideTask
+		onSuccess      []sideTask
+		onFailure      []sideTask
+		finally        []sideTask
+		statusHooks    []func(taskID string, state starriver.TaskStatus)
 	}
 )
 
@@ -31,6 +38,18 @@ func (p *pipeline) GetName() string {
 	return p.Name
 }
 
+func (p *pipeline) GetConf() starriver.PipelineConf {
+	return p.conf
+}
+
+// PipelineConf returns the configure of the pipeline built by BuildPipeline, false for the other implementations
+func PipelineConf(p starriver.Pipeline) (starriver.PipelineConf, bool) {
+	if pl, ok := p.(*pipeline); ok {
+		return pl.conf, true
+	}
+	return starriver.PipelineConf{}, false
+}
+
 func (p *pipeline) GetTaskConfigure(taskId string) starriver.TaskConfigure {
 	if config, ok := p.TaskConfigures[taskId]; ok {
 		return config
@@ -58,17 +77,51 @@ func (p *pipeline) GetTaskStatus(taskID string) starriver.TaskStatus {
 
 func (p *pipeline) SetTaskStatus(taskID string, state starriver.TaskStatus) bool {
 	p.lock.Lock()
-	defer p.lock.Unlock()
 	switch p.TaskStatuses[taskID] {
 	case starriver.TaskStatusSuccess, starriver.TaskStatusSkipped, starriver.TaskStatusFailure:
+		p.lock.Unlock()
 		return false
 	case starriver.TaskStatusBlocked, starriver.TaskStatusInit:
 		p.TaskStatuses[taskID] = state
+	default:
+		p.lock.Unlock()
+		return true
+	}
+	hooks := p.statusHooks
+	p.lock.Unlock()
+	// the hooks are called out of the lock, they may read the task statuses
+	for _, hook := range hooks {
+		hook(taskID, state)
+	}
+	return true
+}
+
+// OnTaskStatus adds the hook called with every status transition of the tasks of the pipeline built by BuildPipeline,
+// false for the other implementations
+func OnTaskStatus(p starriver.Pipeline, hook func(taskID string, state starriver.TaskStatus)) bool {
+	pl, ok := p.(*pipeline)
+	if !ok {
+		return false
 	}
+	pl.lock.Lock()
+	defer pl.lock.Unlock()
+	pl.statusHooks = append(pl.statusHooks, hook)
 	return true
 }
 
-func (p *pipeline) checkBlocked(dataContext starriver.DataContext) *starriver.Result {
+// skipped returns the skipped tasks sorted by id
+func (p *pipeline) skipped() []string {
+	var tasks []string
+	for taskID, taskStatus := range p.TaskStatuses {
+		if taskStatus == starriver.TaskStatusSkipped {
+			tasks = append(tasks, taskID)
+		}
+	}
+	sort.Strings(tasks)
+	return tasks
+}
+
+func (p *pipeline) checkBlocked(dataContext starriver.DataContext, startedAt time.Time) *starriver.Result {
 	for _, taskStatus := range p.TaskStatuses {
 		if taskStatus != starriver.TaskStatusBlocked {
 			continue
@@ -81,7 +134,10 @@ func (p *pipeline) checkBlocked(dataContext starriver.DataContext) *starriver.Re
 		return &starriver.Result{
 			Status:   p.status,
 			State:    p.TaskStatuses,
+			Skipped:  p.skipped(),
+			Attempts: p.walker.Attempts(),
 			Snapshot: snapshot,
+			Report:   p.walker.Report(startedAt),
 		}
 	}
 	return nil
@@ -117,26 +173,38 @@ func (p *pipeline) assembleResult(dataContext starriver.DataContext) map[string]
 
 func (p *pipeline) Run(dataContext starriver.DataContext) starriver.Result {
 	defer dataContext.Release()
+	startedAt := time.Now()
 	if p.Timeout != nil {
 		cancel := dataContext.WithTimeout(*p.Timeout)
 		defer cancel()
 	}
 	if err := p.walker.Walk(p.Graph, dataContext); err != nil {
 		p.status = starriver.PipelineStatusFailure
-		return starriver.Result{
-			Status: p.status,
-			State:  p.TaskStatuses,
-			Error:  err,
+		result := starriver.Result{
+			Status:   p.status,
+			State:    p.TaskStatuses,
+			Skipped:  p.skipped(),
+			Attempts: p.walker.Attempts(),
+			Error:    err,
+			Report:   p.walker.Report(startedAt),
 		}
+		result.Compensations = p.compensate(dataContext)
+		result.Hooks = p.runHooks(dataContext, result)
+		return result
 	}
-	if result := p.checkBlocked(dataContext); result != nil {
+	if result := p.checkBlocked(dataContext, startedAt); result != nil {
 		return *result
 	}
 	p.status = starriver.PipelineStatusSuccess
 	data := p.assembleResult(dataContext)
-	return starriver.Result{
-		Data:   data,
-		Status: p.status,
-		State:  p.TaskStatuses,
+	result := starriver.Result{
+		Data:     data,
+		Status:   p.status,
+		State:    p.TaskStatuses,
+		Skipped:  p.skipped(),
+		Attempts: p.walker.Attempts(),
+		Report:   p.walker.Report(startedAt),
 	}
+	result.Hooks = p.runHooks(dataContext, result)
+	return result
 }
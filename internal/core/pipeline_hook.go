@@ -0,0 +1,72 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/thanksloving/starriver"
+)
+
+// newHookTasks builds the on_success, on_failure or finally tasks, they are components without dependencies
+func newHookTasks(kind string, tasks []starriver.Task) ([]sideTask, error) {
+	hooks := make([]sideTask, 0, len(tasks))
+	for _, task := range tasks {
+		if strings.HasPrefix(task.Name, starriver.BuiltinNodePrefix) {
+			return nil, fmt.Errorf("%s task %q: builtin node %q is not supported", kind, task.ID, task.Name)
+		}
+		if len(task.Depends) > 0 {
+			return nil, fmt.Errorf("%s task %q: depends is not supported", kind, task.ID)
+		}
+		hook, err := newSideTask(task.ID, task.Name, task.Namespace, task.Config)
+		if err != nil {
+			return nil, fmt.Errorf("%s task %q: %v", kind, task.ID, err)
+		}
+		hooks = append(hooks, hook)
+	}
+	return hooks, nil
+}
+
+// runHooks runs the on_success or on_failure tasks then the finally tasks after the walk, even if the run was stopped.
+// The blocked pipeline does not end, so the hooks run when it's resumed and ends.
+func (p *pipeline) runHooks(dataContext starriver.DataContext, result starriver.Result) []starriver.TaskRecord {
+	var kind string
+	var hooks []sideTask
+	switch result.Status {
+	case starriver.PipelineStatusSuccess:
+		kind, hooks = "on_success", p.onSuccess
+	case starriver.PipelineStatusFailure:
+		kind, hooks = "on_failure", p.onFailure
+	default:
+		return nil
+	}
+	if len(hooks) == 0 && len(p.finally) == 0 {
+		return nil
+	}
+	state := make(map[string]starriver.TaskStatus, len(result.State))
+	for taskID, status := range result.State {
+		state[taskID] = status
+	}
+	lookup := func(key string) (interface{}, bool) {
+		switch key {
+		case starriver.HookKeyStatus:
+			return string(result.Status), true
+		case starriver.HookKeyState:
+			return state, true
+		case starriver.HookKeyError:
+			if result.Error != nil {
+				return result.Error.Error(), true
+			}
+		}
+		return nil, false
+	}
+	ctx := context.WithoutCancel(dataContext.Context())
+	records := make([]starriver.TaskRecord, 0, len(hooks)+len(p.finally))
+	for _, hook := range hooks {
+		records = append(records, hook.run(ctx, dataContext, kind+" "+hook.id, lookup))
+	}
+	for _, hook := range p.finally {
+		records = append(records, hook.run(ctx, dataContext, "finally "+hook.id, lookup))
+	}
+	return records
+}
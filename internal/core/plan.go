@@ -0,0 +1,47 @@
+package core
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/thanksloving/starriver"
+)
+
+// Plan walks the pipeline without executing the components, the parameters are prepared and the conditions are
+// evaluated against the data of the data context. The pipeline is used up by the plan, build another one to run.
+func Plan(dataContext starriver.DataContext, p starriver.Pipeline) (starriver.Plan, error) {
+	pl, ok := p.(*pipeline)
+	if !ok {
+		dataContext.Release()
+		return starriver.Plan{}, fmt.Errorf("plan of the pipeline %T is not supported", p)
+	}
+	return pl.plan(dataContext), nil
+}
+
+func (p *pipeline) plan(dataContext starriver.DataContext) starriver.Plan {
+	defer dataContext.Release()
+	p.walker.dryRun = true
+	if err := p.walker.Walk(p.Graph, dataContext); err != nil {
+		dataContext.Debugf("[pipeline]%q plan error %v", p.Name, err)
+	}
+	report := p.walker.Report(time.Now())
+	plan := starriver.Plan{Tasks: report.Tasks}
+	for _, record := range report.Tasks {
+		switch {
+		case record.Status == starriver.TaskStatusSuccess:
+			plan.Run = append(plan.Run, record.TaskID)
+		case record.Status == starriver.TaskStatusFailure:
+			plan.Fail = append(plan.Fail, record.TaskID)
+		case record.Status == starriver.TaskStatusSkipped, record.SkipReason != "":
+			plan.Skip = append(plan.Skip, record.TaskID)
+		}
+	}
+	sort.Strings(plan.Run)
+	sort.Strings(plan.Skip)
+	sort.Strings(plan.Fail)
+	p.walker.recordLock.Lock()
+	plan.Missing = p.walker.missing
+	p.walker.recordLock.Unlock()
+	return plan
+}
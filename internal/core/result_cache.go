@@ -0,0 +1,54 @@
+package core
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+
+	"github.com/thanksloving/starriver"
+)
+
+// WithResultCache installs the cache into the created data context, it must be called before the run.
+func WithResultCache(sc starriver.DataContext, cache starriver.ResultCache) {
+	if dc, ok := sc.(*dataContext); ok {
+		dc.ctx = starriver.ContextWithResultCache(dc.ctx, cache)
+	}
+}
+
+// cacheKey returns the key of the task outputs, it's made of the scope, the component resolved by the builder and the hash of the rendered
+// key template parsed by the builder or the whole parameter
+func (walker *GraphWalker) cacheKey(dataContext starriver.DataContext, taskID string, policy *starriver.CachePolicy, param interface{}) (string, error) {
+	var content []byte
+	if tmpl, ok := walker.cacheKeys[taskID]; ok {
+		var buf bytes.Buffer
+		if err := tmpl.Execute(&buf, param); err != nil {
+			return "", err
+		}
+		content = buf.Bytes()
+	} else {
+		var err error
+		if content, err = json.Marshal(param); err != nil {
+			return "", err
+		}
+	}
+	scope := policy.Scope
+	var scopeID string
+	switch scope {
+	case starriver.CacheScopeGlobal:
+	case starriver.CacheScopeRequest:
+		scopeID = dataContext.GetRequestID()
+	default:
+		scope, scopeID = starriver.CacheScopePipeline, walker.Pipeline.GetName()
+	}
+	return fmt.Sprintf("%s:%s/%s/%x", scope, scopeID, walker.cacheNames[taskID], sha256.Sum256(content)), nil
+}
+
+func (walker *GraphWalker) recordCacheHit(taskID string) {
+	walker.recordLock.Lock()
+	defer walker.recordLock.Unlock()
+	if walker.cacheHits == nil {
+		walker.cacheHits = make(map[string]bool)
+	}
+	walker.cacheHits[taskID] = true
+}
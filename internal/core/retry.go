@@ -0,0 +1,109 @@
+package core
+
+import (
+	"context"
+	"math"
+	"math/rand"
+	"time"
+
+	"github.com/thanksloving/starriver"
+)
+
+const (
+	defaultRetryInterval    = 100 * time.Millisecond
+	defaultMaxRetryInterval = time.Hour // the upper bound of the exponential wait without max_interval
+)
+
+type (
+	// attemptDataContext exposes the current attempt of a task through starriver.GetAttempts
+	attemptDataContext struct {
+		starriver.DataContext
+		ctx      context.Context
+		attempts int
+	}
+
+	retryPolicy struct {
+		*starriver.RetryPolicy
+	}
+)
+
+func newAttemptDataContext(dataContext starriver.DataContext) *attemptDataContext {
+	adc := &attemptDataContext{DataContext: dataContext}
+	adc.next()
+	return adc
+}
+
+func (adc *attemptDataContext) next() {
+	adc.attempts++
+	adc.ctx = starriver.WithAttempts(adc.DataContext.Context(), adc.attempts)
+}
+
+func (adc *attemptDataContext) Context() context.Context {
+	return adc.ctx
+}
+
+func (adc *attemptDataContext) Value(key any) any {
+	return adc.ctx.Value(key)
+}
+
+// retryable check if the response should be retried after the given attempts
+func (rp retryPolicy) retryable(resp starriver.Response, attempts int) bool {
+	if rp.RetryPolicy == nil || attempts >= rp.MaxAttempts {
+		return false
+	}
+	if resp.GetStatus() == starriver.TaskStatusBlocked {
+		return false
+	}
+	level := resp.GetFailureLevel()
+	if level == starriver.FailureLevelNormal {
+		return false
+	}
+	if len(rp.RetryOn) == 0 {
+		return level == starriver.FailureLevelError
+	}
+	for _, retryOn := range rp.RetryOn {
+		if retryOn == level {
+			return true
+		}
+	}
+	return false
+}
+
+// backoff returns the wait before the next attempt
+func (rp retryPolicy) backoff(attempts int) time.Duration {
+	interval := defaultRetryInterval
+	if rp.Interval != nil {
+		interval = *rp.Interval
+	}
+	if rp.Backoff == starriver.BackoffExponential {
+		maxInterval := defaultMaxRetryInterval
+		if rp.MaxInterval != nil {
+			maxInterval = *rp.MaxInterval
+		}
+		// compare in float64, the wait of the late attempts overflows time.Duration
+		if wait := float64(interval) * math.Pow(2, float64(attempts-1)); wait < float64(maxInterval) {
+			interval = time.Duration(wait)
+		} else {
+			interval = maxInterval
+		}
+	}
+	if jitter := math.Min(math.Max(rp.Jitter, 0), 1); jitter > 0 {
+		interval += time.Duration((rand.Float64()*2 - 1) * jitter * float64(interval))
+	}
+	if interval < 0 {
+		return 0
+	}
+	return interval
+}
+
+// wait sleeps before the next attempt, false if the data context is done in the meantime
+func (rp retryPolicy) wait(dataContext starriver.DataContext, attempts int) bool {
+	timer := time.NewTimer(rp.backoff(attempts))
+	defer timer.Stop()
+	select {
+	case <-dataContext.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
@@ -0,0 +1,134 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/thanksloving/starriver"
+	"github.com/thanksloving/starriver/helper"
+	"github.com/thanksloving/starriver/registry"
+)
+
+type (
+	// sideTask runs outside the walk of the graph, such as the compensation and the finally task
+	sideTask struct {
+		id         string
+		name       string
+		executable starriver.Executable
+		config     starriver.TaskConfigure
+	}
+
+	// detachedDataContext is not cancelled with the run, lookup finds the data before the data context
+	detachedDataContext struct {
+		starriver.DataContext
+		ctx    context.Context
+		lookup func(key string) (interface{}, bool)
+	}
+)
+
+// newSideTask finds the component by name, the executable has the id
+func newSideTask(id, name string, namespace *string, config starriver.TaskConfigure) (sideTask, error) {
+	component := registry.GetComponent(name, namespace)
+	if component == nil {
+		return sideTask{}, fmt.Errorf("can not found component with name %q", name)
+	}
+	if config.Timeout == nil {
+		config.Timeout = component.Timeout
+	}
+	return sideTask{id: id, name: name, executable: component.Executor(id), config: config}, nil
+}
+
+// run executes the task with ctx, which should not be cancelled with the run
+func (st sideTask) run(ctx context.Context, dataContext starriver.DataContext, span string, lookup func(key string) (interface{}, bool)) starriver.TaskRecord {
+	record := starriver.TaskRecord{TaskID: st.id, ReadyAt: time.Now(), StartedAt: time.Now()}
+	ctx, s := starriver.StartSpan(ctx, span,
+		starriver.Attr(starriver.AttrTaskID, st.id),
+		starriver.Attr(starriver.AttrComponent, st.name),
+	)
+	defer s.End()
+	if st.config.Timeout != nil {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, *st.config.Timeout)
+		defer cancel()
+	}
+	resp, attempts := st.execute(&detachedDataContext{DataContext: dataContext, ctx: ctx, lookup: lookup})
+	record.EndedAt = time.Now()
+	record.Status = resp.GetStatus()
+	record.FailureLevel = resp.GetFailureLevel()
+	record.Attempts = attempts
+	if err := resp.GetError(); err != nil {
+		record.Error = err.Error()
+	}
+	s.SetAttributes(starriver.Attr(starriver.AttrTaskStatus, string(record.Status)), starriver.Attr(starriver.AttrAttempts, attempts))
+	s.RecordError(resp.GetError())
+	if !resp.IsPass() {
+		dataContext.Errorf("[%s]component %q failed, err=%v", span, st.name, resp.GetError())
+	}
+	return record
+}
+
+func (st sideTask) execute(dataContext starriver.DataContext) (resp starriver.Response, attempts int) {
+	attemptContext := newAttemptDataContext(dataContext)
+	defer func() {
+		if r := recover(); r != nil {
+			resp = helper.NewErrorResponse(fmt.Errorf("%q executable execute panic, %v", st.id, r))
+		}
+		attempts = attemptContext.attempts
+	}()
+	var param interface{}
+	if p, ok := st.executable.(starriver.WithParameters); ok {
+		var err error
+		ap := &assembleParam{id: st.id}
+		if param, err = ap.prepareParameter(dataContext, st.config.Params, p.ParameterNew()); err != nil {
+			return helper.NewErrorResponse(err), 0
+		}
+	}
+	call := func() starriver.Response {
+		if resp := st.executable.Execute(attemptContext, param); resp != nil {
+			return resp
+		}
+		return helper.NewErrorResponse(fmt.Errorf("%q response is nil", st.id))
+	}
+	rp := retryPolicy{st.config.Retry}
+	resp = call()
+	for rp.retryable(resp, attemptContext.attempts) {
+		dataContext.Warnf("component %q attempt %d failed, err=%v", st.id, attemptContext.attempts, resp.GetError())
+		if !rp.wait(dataContext, attemptContext.attempts) {
+			break
+		}
+		attemptContext.next()
+		resp = call()
+	}
+	return resp, attemptContext.attempts
+}
+
+// Get finds the data by lookup first, then the data context
+func (ddc *detachedDataContext) Get(key string) (interface{}, bool) {
+	if ddc.lookup != nil {
+		if val, ok := ddc.lookup(key); ok {
+			return val, ok
+		}
+	}
+	return ddc.DataContext.Get(key)
+}
+
+func (ddc *detachedDataContext) Context() context.Context {
+	return ddc.ctx
+}
+
+func (ddc *detachedDataContext) Deadline() (deadline time.Time, ok bool) {
+	return ddc.ctx.Deadline()
+}
+
+func (ddc *detachedDataContext) Done() <-chan struct{} {
+	return ddc.ctx.Done()
+}
+
+func (ddc *detachedDataContext) Err() error {
+	return ddc.ctx.Err()
+}
+
+func (ddc *detachedDataContext) Value(key any) any {
+	return ddc.ctx.Value(key)
+}
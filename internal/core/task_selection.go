@@ -0,0 +1,86 @@
+package core
+
+import (
+	"fmt"
+
+	"github.com/thanksloving/starriver"
+	"github.com/thanksloving/starriver/internal/dag"
+)
+
+// SelectTasks limits the next run of the pipeline to a sub graph: the targets and their upstream tasks when there
+// are targets, the from tasks, their downstream tasks and the upstream tasks of them when there are from tasks.
+// The from tasks and their downstream tasks are reset to init to run again, the other tasks keep their statuses,
+// so the succeeded ones are reused and the ones out of the sub graph are ignored.
+func SelectTasks(p starriver.Pipeline, targets, from []string) error {
+	pl, ok := p.(*pipeline)
+	if !ok {
+		return fmt.Errorf("select tasks of the pipeline %T is not supported", p)
+	}
+	return pl.selectTasks(targets, from)
+}
+
+func (p *pipeline) selectTasks(targets, from []string) error {
+	if len(targets) == 0 && len(from) == 0 {
+		return nil
+	}
+	var (
+		selected dag.Set
+		err      error
+	)
+	// the edges point to the dependent tasks, so the Descendents of the graph are the upstream tasks
+	if len(targets) > 0 {
+		if selected, err = p.closure(targets, p.Graph.Descendents); err != nil {
+			return err
+		}
+	}
+	if len(from) > 0 {
+		rerun, err := p.closure(from, p.Graph.Ancestors)
+		if err != nil {
+			return err
+		}
+		if selected != nil {
+			rerun = rerun.Intersection(selected)
+		} else if selected, err = p.closure(setIDs(rerun), p.Graph.Descendents); err != nil {
+			return err
+		}
+		p.lock.Lock()
+		for taskID := range rerun {
+			p.TaskStatuses[taskID] = starriver.TaskStatusInit
+		}
+		p.lock.Unlock()
+	}
+	p.Graph = p.Graph.SubDAG(selected)
+	return nil
+}
+
+// closure returns the tasks and the tasks reached from them by the walk
+func (p *pipeline) closure(taskIDs []string, walk func(dag.Vertex) (dag.Set, error)) (dag.Set, error) {
+	vertices := make(map[string]dag.Vertex)
+	for _, v := range p.Graph.Vertices() {
+		vertices[v.ID()] = v
+	}
+	set := make(dag.Set)
+	for _, taskID := range taskIDs {
+		v, ok := vertices[taskID]
+		if !ok {
+			return nil, fmt.Errorf("task %q not found in the pipeline %q", taskID, p.Name)
+		}
+		reached, err := walk(v)
+		if err != nil {
+			return nil, err
+		}
+		set.Add(v)
+		for _, o := range reached {
+			set.Add(o)
+		}
+	}
+	return set, nil
+}
+
+func setIDs(set dag.Set) []string {
+	taskIDs := make([]string, 0, len(set))
+	for taskID := range set {
+		taskIDs = append(taskIDs, taskID)
+	}
+	return taskIDs
+}
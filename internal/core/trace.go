@@ -0,0 +1,24 @@
+package core
+
+import (
+	"github.com/thanksloving/starriver"
+)
+
+// WithTracer installs the tracer into the created data context, it must be called before the run.
+func WithTracer(sc starriver.DataContext, tracer starriver.Tracer) {
+	if dc, ok := sc.(*dataContext); ok {
+		dc.ctx = starriver.ContextWithTracer(dc.ctx, tracer)
+	}
+}
+
+// StartSpan starts a span under the current span of the data context, the spans of the tasks run later are its children.
+func StartSpan(sc starriver.DataContext, name string, attributes ...starriver.Attribute) starriver.Span {
+	dc, ok := sc.(*dataContext)
+	if !ok {
+		_, span := starriver.StartSpan(sc.Context(), name, attributes...)
+		return span
+	}
+	var span starriver.Span
+	dc.ctx, span = starriver.StartSpan(dc.ctx, name, attributes...)
+	return span
+}
@@ -0,0 +1,328 @@
+package core
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/thanksloving/starriver"
+	"github.com/thanksloving/starriver/internal/dag"
+	"github.com/thanksloving/starriver/internal/expr"
+	"github.com/thanksloving/starriver/registry"
+)
+
+type (
+	// ValidationError describes one problem of a pipeline configure, Path locates the problem such as pipeline[3].config.params[1]
+	ValidationError struct {
+		Path    string `json:"path"`
+		Message string `json:"message"`
+	}
+
+	validator struct {
+		errs []ValidationError
+	}
+)
+
+var (
+	paramTypes = map[starriver.ParamType]struct{}{
+		starriver.ParamTypeLiteral:  {},
+		starriver.ParamTypeVariable: {},
+		starriver.ParamTypeComplex:  {},
+		starriver.ParamTypeMapping:  {},
+	}
+
+	conditionOperators = map[starriver.ConditionOperator]struct{}{
+		starriver.ConditionGT: {},
+		starriver.ConditionLT: {},
+		starriver.ConditionLE: {},
+		starriver.ConditionGE: {},
+		starriver.ConditionEQ: {},
+		starriver.ConditionNE: {},
+		starriver.ConditionIn: {},
+	}
+)
+
+func (ve ValidationError) Error() string {
+	return fmt.Sprintf("%s: %s", ve.Path, ve.Message)
+}
+
+// Validate checks the pipeline configure statically and reports all the problems at once, nil means it's valid
+func Validate(pc starriver.PipelineConf) []ValidationError {
+	v := &validator{}
+	taskIndexes := make(map[string]int, len(pc.Pipeline))
+	for idx, task := range pc.Pipeline {
+		path := fmt.Sprintf("pipeline[%d]", idx)
+		if task.ID == "" {
+			v.add(path+".task", "task id is required")
+		} else if prev, ok := taskIndexes[task.ID]; ok {
+			v.add(path+".task", "duplicate task id %q, already defined by pipeline[%d]", task.ID, prev)
+		} else {
+			taskIndexes[task.ID] = idx
+		}
+		v.validateTask(path, task)
+	}
+	for idx, task := range pc.Pipeline {
+		for i, depend := range task.Depends {
+			path := fmt.Sprintf("pipeline[%d].depends[%d]", idx, i)
+			source, ok := taskIndexes[depend.ID]
+			if !ok {
+				v.add(path+".task", "unknown depend task id %q", depend.ID)
+			}
+			v.validateCondition(path+".condition", depend)
+			if ok {
+				v.validateBranch(path, depend, pc.Pipeline[source])
+			}
+		}
+	}
+	v.validateHooks(pc, taskIndexes)
+	v.validateGraph(pc, taskIndexes)
+	return v.errs
+}
+
+// validateHooks checks the on_success, on_failure and finally tasks, they are components without dependencies
+func (v *validator) validateHooks(pc starriver.PipelineConf, taskIndexes map[string]int) {
+	defined := make(map[string]string)
+	for _, hooks := range []struct {
+		kind  string
+		tasks []starriver.Task
+	}{{"on_success", pc.OnSuccess}, {"on_failure", pc.OnFailure}, {"finally", pc.Finally}} {
+		for idx, task := range hooks.tasks {
+			path := fmt.Sprintf("%s[%d]", hooks.kind, idx)
+			if task.ID == "" {
+				v.add(path+".task", "task id is required")
+			} else if prev, ok := taskIndexes[task.ID]; ok {
+				v.add(path+".task", "duplicate task id %q, already defined by pipeline[%d]", task.ID, prev)
+			} else if prev, ok := defined[task.ID]; ok {
+				v.add(path+".task", "duplicate task id %q, already defined by %s", task.ID, prev)
+			} else {
+				defined[task.ID] = path
+			}
+			if len(task.Depends) > 0 {
+				v.add(path+".depends", "%s task does not support depends", hooks.kind)
+			}
+			if strings.HasPrefix(task.Name, starriver.BuiltinNodePrefix) {
+				v.add(path+".name", "%s task does not support builtin node %q", hooks.kind, task.Name)
+				continue
+			}
+			v.validateTask(path, task)
+		}
+	}
+}
+
+func (v *validator) add(path, format string, args ...interface{}) {
+	v.errs = append(v.errs, ValidationError{Path: path, Message: fmt.Sprintf(format, args...)})
+}
+
+func (v *validator) validateTask(path string, task starriver.Task) {
+	if task.TriggerRule != "" {
+		if strings.HasPrefix(task.Name, starriver.BuiltinNodePrefix) {
+			v.add(path+".trigger_rule", "builtin node %q does not support trigger rule", task.Name)
+		} else if _, err := dag.ParseTriggerRule(task.TriggerRule); err != nil {
+			v.add(path+".trigger_rule", "%v", err)
+		}
+	}
+	if task.Compensate != nil {
+		v.validateCompensate(path+".compensate", task)
+	}
+	if task.Config.Cache != nil {
+		v.validateCache(path+".config.cache", task)
+	}
+	if task.Name == switchNodeName {
+		v.validateSwitch(path+".switch", task.Switch)
+	} else if task.Switch != nil {
+		v.add(path+".switch", "switch is only for the %s node", switchNodeName)
+	}
+	if strings.HasPrefix(task.Name, starriver.BuiltinNodePrefix) {
+		if registry.NewBuiltinNode(task.ID, task.Name) == nil {
+			v.add(path+".name", "unknown builtin node %q", task.Name)
+		}
+		return
+	}
+	component := registry.GetComponent(task.Name, task.Namespace)
+	if component == nil {
+		v.add(path+".name", "can not found component with name %q", task.Name)
+		return
+	}
+	var paramObj reflect.Value
+	p, withParameters := component.Executor(task.ID).(starriver.WithParameters)
+	if withParameters {
+		if obj := reflect.ValueOf(p.ParameterNew()); obj.Kind() == reflect.Pointer && obj.Elem().Kind() == reflect.Struct {
+			paramObj = obj.Elem()
+		}
+	}
+	if cache := task.Config.Cache; cache != nil && cache.Key == "" && !withParameters {
+		// the key would be the hash of the nil parameter, all the calls share it
+		v.add(path+".config.cache.key", "cache key is required, component %q has no parameters", task.Name)
+	}
+	configured := make(map[string]struct{}, len(task.Config.Params))
+	for idx, param := range task.Config.Params {
+		paramPath := fmt.Sprintf("%s.config.params[%d]", path, idx)
+		configured[param.Name] = struct{}{}
+		switch {
+		case param.Name == "":
+			v.add(paramPath, "param name is required")
+		case !paramObj.IsValid():
+			v.add(paramPath, "component %q does not accept parameters", task.Name)
+		case !paramObj.FieldByName(param.Name).IsValid():
+			v.add(paramPath, "param %q does not exist on component %q", param.Name, task.Name)
+		}
+		v.validateParam(paramPath, param)
+	}
+	for _, input := range component.Input {
+		if _, ok := configured[input.Key]; input.Required && !ok {
+			v.add(path+".config.params", "required param %q of component %q is missing", input.Key, task.Name)
+		}
+	}
+}
+
+func (v *validator) validateParam(path string, param starriver.Param) {
+	if _, ok := paramTypes[param.Type]; !ok {
+		v.add(path+".type", "unknown param type %q", param.Type)
+		return
+	}
+	switch param.Type {
+	case starriver.ParamTypeVariable:
+		if param.Variable == "" {
+			v.add(path+".variable", "variable is required when the param type is %q", param.Type)
+		}
+	case starriver.ParamTypeComplex:
+		for idx, item := range param.Complex {
+			v.validateParam(fmt.Sprintf("%s.complex[%d]", path, idx), item)
+		}
+	case starriver.ParamTypeMapping:
+		keys := make([]string, 0, len(param.Mapping))
+		for key := range param.Mapping {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			v.validateParam(fmt.Sprintf("%s.mapping[%s]", path, key), param.Mapping[key])
+		}
+	}
+}
+
+func (v *validator) validateCondition(path string, depend starriver.Depend) {
+	if depend.Condition == nil {
+		return
+	}
+	if depend.Condition.Expr != "" {
+		if _, err := expr.Compile(depend.Condition.Expr); err != nil {
+			v.add(path+".expr", "invalid condition expr: %v", err)
+		}
+		return
+	}
+	if depend.Condition.Key == "" {
+		v.add(path+".key", "condition key is required")
+	}
+	if _, ok := conditionOperators[depend.Condition.Operator]; !ok {
+		v.add(path+".operator", "unknown condition operator %q", depend.Condition.Operator)
+	}
+}
+
+func (v *validator) validateCompensate(path string, task starriver.Task) {
+	if strings.HasPrefix(task.Name, starriver.BuiltinNodePrefix) {
+		v.add(path, "builtin node %q does not support compensate", task.Name)
+		return
+	}
+	if registry.GetComponent(task.Compensate.Name, task.Compensate.Namespace) == nil {
+		v.add(path+".name", "can not found compensate component with name %q", task.Compensate.Name)
+	}
+	for idx, param := range task.Compensate.Config.Params {
+		v.validateParam(fmt.Sprintf("%s.config.params[%d]", path, idx), param)
+	}
+}
+
+func (v *validator) validateCache(path string, task starriver.Task) {
+	if strings.HasPrefix(task.Name, starriver.BuiltinNodePrefix) {
+		v.add(path, "builtin node %q does not support cache", task.Name)
+		return
+	}
+	cache := task.Config.Cache
+	switch cache.Scope {
+	case "", starriver.CacheScopePipeline, starriver.CacheScopeGlobal, starriver.CacheScopeRequest:
+	default:
+		v.add(path+".scope", "unknown cache scope %q", cache.Scope)
+	}
+	if cache.TTL != nil && *cache.TTL < 0 {
+		v.add(path+".ttl", "cache ttl can not be negative")
+	}
+	if cache.Key != "" {
+		if _, err := template.New(task.ID).Parse(cache.Key); err != nil {
+			v.add(path+".key", "invalid cache key template: %v", err)
+		}
+	}
+}
+
+func (v *validator) validateSwitch(path string, sw *starriver.Switch) {
+	switch {
+	case sw == nil || (sw.Key == "" && sw.Expr == ""):
+		v.add(path, "switch key or expr is required")
+	case sw.Expr != "":
+		if _, err := expr.Compile(sw.Expr); err != nil {
+			v.add(path+".expr", "invalid switch expr: %v", err)
+		}
+	}
+}
+
+// validateBranch checks the depend of the @switch node has a branch, and only it has
+func (v *validator) validateBranch(path string, depend starriver.Depend, source starriver.Task) {
+	switch {
+	case source.Name != switchNodeName:
+		if depend.Branch != "" {
+			v.add(path+".branch", "branch is only for depending on the %s node, %q is %q", switchNodeName, source.ID, source.Name)
+		}
+	case depend.Branch == "":
+		v.add(path+".branch", "branch is required when depending on the %s node %q", switchNodeName, source.ID)
+	case depend.Condition != nil:
+		v.add(path+".condition", "condition can not be used with branch")
+	}
+}
+
+// validateGraph checks the structure of the pipeline with the resolved tasks, the tasks without id or with a duplicate
+// id and the unknown depends are reported by Validate, so they are left out of the graph.
+func (v *validator) validateGraph(pc starriver.PipelineConf, taskIndexes map[string]int) {
+	nodes := make(map[string]dag.Vertex, len(taskIndexes))
+	graph := dag.Graph{}
+	for idx, task := range pc.Pipeline {
+		if index, ok := taskIndexes[task.ID]; ok && index == idx {
+			nodes[task.ID] = graph.Add(dag.NewNode(task.ID, ""))
+		}
+	}
+	resolved := true
+	for idx, task := range pc.Pipeline {
+		if index, ok := taskIndexes[task.ID]; !ok || index != idx {
+			resolved = false
+			continue
+		}
+		for i, depend := range task.Depends {
+			if depend.ID == task.ID {
+				v.add(fmt.Sprintf("pipeline[%d].depends[%d].task", idx, i), "task %q depends on itself", task.ID)
+				continue
+			}
+			source, ok := nodes[depend.ID]
+			if !ok {
+				resolved = false
+				continue
+			}
+			graph.Connect(dag.BasicEdge(source, nodes[task.ID]))
+		}
+	}
+	acyclicGraph := dag.NewDAG(graph)
+	for _, cycle := range acyclicGraph.Cycles() {
+		ids := make([]string, len(cycle))
+		for idx, vertex := range cycle {
+			ids[idx] = vertex.ID()
+		}
+		sort.Strings(ids)
+		v.add("pipeline", "cycle: %s", strings.Join(ids, ", "))
+	}
+	if !resolved {
+		// the roots are not reliable without the whole tasks and depends
+		return
+	}
+	if _, err := acyclicGraph.Root(); err != nil {
+		v.add("pipeline", "%v", err)
+	}
+}
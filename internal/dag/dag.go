@@ -14,6 +14,7 @@ type (
 	// acyclicGraph is a specialization of Graph that cannot have cycles.
 	acyclicGraph struct {
 		Graph
+		triggerRules map[string]TriggerRule
 	}
 
 	DAG interface {
@@ -26,7 +27,10 @@ type (
 		TransitiveReduction()
 		Validate() error
 		Cycles() [][]Vertex
-		Walk(dataContext starriver.DataContext, cb WalkFunc) starriver.Responses
+		TopologicalOrder() []Vertex
+		ReverseTopologicalOrder() []Vertex
+		SubDAG(vertices Set) DAG
+		Walk(dataContext starriver.DataContext, cb WalkFunc) (starriver.Responses, []starriver.TaskRecord)
 	}
 
 	// WalkFunc is the callback used for walking the graph
@@ -85,6 +89,22 @@ func (g *acyclicGraph) Descendents(v Vertex) (Set, error) {
 	return s, nil
 }
 
+// SubDAG returns the DAG of the vertices and the edges between them, the trigger rules are kept.
+func (g *acyclicGraph) SubDAG(vertices Set) DAG {
+	var graph Graph
+	for _, v := range g.Vertices() {
+		if vertices.Include(v) {
+			graph.Add(v)
+		}
+	}
+	for _, e := range g.Edges() {
+		if vertices.Include(e.Source()) && vertices.Include(e.Target()) {
+			graph.Connect(e)
+		}
+	}
+	return NewDAG(graph, WithTriggerRules(g.triggerRules))
+}
+
 func (g *acyclicGraph) Leaves() ([]Vertex, error) {
 	leaves := make([]Vertex, 0)
 	for _, v := range g.Vertices() {
@@ -201,14 +221,16 @@ func (g *acyclicGraph) Cycles() [][]Vertex {
 // Walk walks the graph, calling your callback as each node is visited.
 // This will walk nodes in parallel if it can. The resulting diagnostics
 // contains problems from all graphs visited, in no particular order.
-func (g *acyclicGraph) Walk(dataContext starriver.DataContext, cb WalkFunc) starriver.Responses {
+// The records of the visited vertices are returned as well.
+func (g *acyclicGraph) Walk(dataContext starriver.DataContext, cb WalkFunc) (starriver.Responses, []starriver.TaskRecord) {
 	w := &Walker{
-		DataContext: dataContext,
-		Callback:    cb,
-		Reverse:     false,
+		DataContext:  dataContext,
+		Callback:     cb,
+		Reverse:      false,
+		TriggerRules: g.triggerRules,
 	}
 	w.Update(g)
-	return w.Wait()
+	return w.Wait(), w.Records()
 }
 
 type vertexAtDepth struct {
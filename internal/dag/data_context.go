@@ -14,10 +14,9 @@ type nodeDataContext struct {
 	starriver.DataContext
 }
 
-func newNodeDataContext(dataContext starriver.DataContext, timeout *time.Duration) (newContext *nodeDataContext, cancel context.CancelFunc) {
-	ctx := dataContext.Context()
+func newNodeDataContext(ctx context.Context, dataContext starriver.DataContext, timeout *time.Duration) (newContext *nodeDataContext, cancel context.CancelFunc) {
 	if timeout != nil {
-		ctx, cancel = context.WithTimeout(dataContext.Context(), *timeout)
+		ctx, cancel = context.WithTimeout(ctx, *timeout)
 	}
 	return &nodeDataContext{
 		ctx:         ctx,
@@ -7,6 +7,7 @@ import (
 	"github.com/spf13/cast"
 
 	"github.com/thanksloving/starriver"
+	"github.com/thanksloving/starriver/internal/expr"
 )
 
 // Edge represents an edge in the graph, with a source and target vertex.
@@ -22,6 +23,8 @@ type (
 
 	IsConditionalEdge interface {
 		Match(ctx starriver.DataContext) bool
+		// Condition describes the condition, such as "score > 60"
+		Condition() string
 	}
 
 	// basicEdge is a basic implementation of Edge that has the source and
@@ -37,6 +40,11 @@ type (
 		value    interface{}
 		operator starriver.ConditionOperator
 	}
+
+	expressionEdge struct {
+		basicEdge
+		expression *expr.Expression
+	}
 )
 
 // BasicEdge returns an Edge implementation that simply tracks the source
@@ -57,6 +65,16 @@ func ConditionEdge(source, target Vertex, key string, value interface{}, operato
 	}
 }
 
+// ExpressionEdge return an Edge with a compiled boolean expression as condition
+func ExpressionEdge(source, target Vertex, expression *expr.Expression) Edge {
+	return &expressionEdge{
+		basicEdge: basicEdge{
+			S: source, T: target,
+		},
+		expression: expression,
+	}
+}
+
 // PropertyEdge return an Edge with Property
 func PropertyEdge(source, target Vertex, properties map[string]interface{}) Edge {
 	return &basicEdge{
@@ -88,6 +106,16 @@ func (e *basicEdge) WithProperties(properties map[string]interface{}) Edge {
 	return e
 }
 
+// WithProperties keeps the condition, the one of basicEdge would return the embedded edge only
+func (c *conditionEdge) WithProperties(properties map[string]interface{}) Edge {
+	c.basicEdge.WithProperties(properties)
+	return c
+}
+
+func (c *conditionEdge) Condition() string {
+	return fmt.Sprintf("%s %s %v", c.key, c.operator, c.value)
+}
+
 func (c *conditionEdge) Match(dc starriver.DataContext) bool {
 	val, ok := dc.Get(c.key)
 	if !ok {
@@ -121,6 +149,25 @@ func (c *conditionEdge) Match(dc starriver.DataContext) bool {
 	return false
 }
 
+func (e *expressionEdge) WithProperties(properties map[string]interface{}) Edge {
+	e.basicEdge.WithProperties(properties)
+	return e
+}
+
+func (e *expressionEdge) Condition() string {
+	return e.expression.String()
+}
+
+// Match evaluates the expression, the variables are resolved by the data context, and the "env." prefix is for the pipeline's env.
+func (e *expressionEdge) Match(dc starriver.DataContext) bool {
+	result, err := e.expression.EvalBool(resolver(dc))
+	if err != nil {
+		dc.Errorf("condition eval error, expr=%q, cause:%v", e.expression, err)
+		return false
+	}
+	return result
+}
+
 func compareNumberValue(source, target interface{}, operator starriver.ConditionOperator) (bool, error) {
 	s, e := cast.ToFloat64E(source)
 	if e != nil {
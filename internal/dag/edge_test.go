@@ -8,6 +8,7 @@ import (
 	"github.com/stretchr/testify/assert"
 
 	"github.com/thanksloving/starriver"
+	"github.com/thanksloving/starriver/internal/expr"
 )
 
 type testDataContext struct {
@@ -170,3 +171,56 @@ func TestConditionEdge(t *testing.T) {
 		assert.Equal(t, test.result, result)
 	}
 }
+
+func TestExpressionEdge(t *testing.T) {
+	g := &acyclicGraph{}
+	a := g.Add(testVertex{1})
+	b := g.Add(testVertex{2})
+	dataContext := testDataContext{
+		data: map[string]interface{}{
+			"tag": "C4",
+			"age": 18,
+		},
+	}
+	tests := []struct {
+		expr   string
+		result bool
+	}{
+		{`tag in ["C4", "C5"] && age >= 18`, true},
+		{`tag == "C4" && age > 18`, false},
+		{`tag > 1`, false},
+	}
+	for _, test := range tests {
+		expression, err := expr.Compile(test.expr)
+		assert.NoError(t, err)
+		edge := ExpressionEdge(a, b, expression)
+		assert.Equal(t, test.result, edge.(IsConditionalEdge).Match(dataContext), test.expr)
+	}
+}
+
+// the condition edge lost its condition when the properties were set, since the method of basicEdge returned itself
+func TestConditionEdge_WithProperties(t *testing.T) {
+	a, b := testVertex{1}, testVertex{2}
+	edge := ConditionEdge(a, b, "tag", "C4", starriver.ConditionEQ).WithProperties(map[string]interface{}{"key": "value"})
+	_, ok := edge.(IsConditionalEdge)
+	assert.True(t, ok)
+	assert.Equal(t, "value", edge.Properties()["key"])
+}
+
+func TestExpressionEdge_WithProperties(t *testing.T) {
+	a, b := testVertex{1}, testVertex{2}
+	expression, err := expr.Compile(`tag == "C4"`)
+	assert.NoError(t, err)
+	edge := ExpressionEdge(a, b, expression).WithProperties(map[string]interface{}{"key": "value"})
+	_, ok := edge.(IsConditionalEdge)
+	assert.True(t, ok)
+	assert.Equal(t, "value", edge.Properties()["key"])
+}
+
+func TestConditionalEdge_Condition(t *testing.T) {
+	a, b := testVertex{1}, testVertex{2}
+	expression, err := expr.Compile(`tag == "C4" && age > 10`)
+	assert.NoError(t, err)
+	assert.Equal(t, "age >= 18", ConditionEdge(a, b, "age", 18, starriver.ConditionGE).(IsConditionalEdge).Condition())
+	assert.Equal(t, `tag == "C4" && age > 10`, ExpressionEdge(a, b, expression).(IsConditionalEdge).Condition())
+}
@@ -4,6 +4,8 @@ const (
 	NodeTypeAny NodeType = "any"
 	// NodeTypeNot NotNode is a special node, it will pass when dependency node failed, so it can only have one dependency node
 	NodeTypeNot NodeType = "not"
+	// NodeTypeSwitch SwitchNode selects one branch of the downstream nodes, the others are skipped
+	NodeTypeSwitch NodeType = "switch"
 )
 
 type (
@@ -0,0 +1,112 @@
+package dag
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cast"
+
+	"github.com/thanksloving/starriver"
+	"github.com/thanksloving/starriver/internal/expr"
+)
+
+// SwitchBranchKey is the output key of the branch selected by the switch node
+const SwitchBranchKey = "branch"
+
+type (
+	// SwitchNode evaluates the key or the expression once, and the downstream nodes on the branch with the same name run
+	SwitchNode interface {
+		Node
+
+		Select(dc starriver.DataContext) (string, error)
+	}
+
+	switchNode struct {
+		node
+		key        string
+		expression *expr.Expression
+		branches   map[string]struct{}
+	}
+
+	branchEdge struct {
+		basicEdge
+		branch string
+	}
+)
+
+// NewSwitchNode returns a switch node, the value of the key is used if the expression is nil
+func NewSwitchNode(id, key string, expression *expr.Expression, branches []string) SwitchNode {
+	sn := &switchNode{
+		node:       node{id: id, types: NodeTypeSwitch},
+		key:        key,
+		expression: expression,
+		branches:   make(map[string]struct{}, len(branches)),
+	}
+	for _, branch := range branches {
+		sn.branches[branch] = struct{}{}
+	}
+	return sn
+}
+
+// Select returns the branch named by the value, it's the default branch if no branch has the name
+func (sn *switchNode) Select(dc starriver.DataContext) (string, error) {
+	var value interface{}
+	if sn.expression != nil {
+		val, err := sn.expression.Eval(resolver(dc))
+		if err != nil {
+			return "", fmt.Errorf("switch eval error, expr=%q, cause:%v", sn.expression, err)
+		}
+		value = val
+	} else {
+		val, ok := dc.Get(sn.key)
+		if !ok {
+			return "", fmt.Errorf("switch eval error, key=%v not exist", sn.key)
+		}
+		value = val
+	}
+	branch, err := cast.ToStringE(value)
+	if err != nil {
+		return "", fmt.Errorf("switch value %v can not be a branch name: %v", value, err)
+	}
+	if _, ok := sn.branches[branch]; !ok {
+		branch = starriver.SwitchDefaultBranch
+	}
+	return branch, nil
+}
+
+// BranchEdge return an Edge which is matched when the switch node source selects the branch
+func BranchEdge(source, target Vertex, branch string) Edge {
+	return &branchEdge{
+		basicEdge: basicEdge{
+			S: source, T: target,
+		},
+		branch: branch,
+	}
+}
+
+func (e *branchEdge) WithProperties(properties map[string]interface{}) Edge {
+	e.basicEdge.WithProperties(properties)
+	return e
+}
+
+func (e *branchEdge) Condition() string {
+	return "branch == " + e.branch
+}
+
+func (e *branchEdge) Match(dc starriver.DataContext) bool {
+	branch, ok := dc.GetDependNodeValue(e.S.ID(), SwitchBranchKey)
+	return ok && branch == e.branch
+}
+
+// resolver resolves the variables of the expression by the data context, and the "env." prefix is for the pipeline's env.
+func resolver(dc starriver.DataContext) expr.Resolver {
+	return func(name string) (interface{}, bool) {
+		if val, ok := dc.Get(name); ok {
+			return val, true
+		}
+		if key, ok := strings.CutPrefix(name, "env."); ok {
+			return dc.Env(key)
+		}
+		return nil, false
+	}
+}
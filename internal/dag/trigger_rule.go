@@ -0,0 +1,79 @@
+package dag
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+
+	"github.com/thanksloving/starriver"
+)
+
+type (
+	// TriggerRule is the parsed starriver.TriggerRule, N is the n of at_least(n)
+	TriggerRule struct {
+		Name starriver.TriggerRule
+		N    int
+	}
+
+	// depsResult counts the results of the dependencies
+	depsResult struct {
+		success, failed, skipped, blocked int
+	}
+)
+
+var atLeastPattern = regexp.MustCompile(`^at_least\((\d+)\)$`)
+
+// ParseTriggerRule parses the rule, empty is all_success
+func ParseTriggerRule(rule starriver.TriggerRule) (TriggerRule, error) {
+	switch rule {
+	case "":
+		return TriggerRule{Name: starriver.TriggerRuleAllSuccess}, nil
+	case starriver.TriggerRuleAllSuccess, starriver.TriggerRuleAllDone, starriver.TriggerRuleOneSuccess,
+		starriver.TriggerRuleOneFailed, starriver.TriggerRuleNoneFailed, starriver.TriggerRuleNoneFailedMinOneSuccess:
+		return TriggerRule{Name: rule}, nil
+	}
+	if matches := atLeastPattern.FindStringSubmatch(string(rule)); matches != nil {
+		n, err := strconv.Atoi(matches[1])
+		if err != nil || n < 1 {
+			return TriggerRule{}, fmt.Errorf("invalid trigger rule %q, n of at_least(n) must be positive", rule)
+		}
+		return TriggerRule{Name: starriver.TriggerRuleAtLeast, N: n}, nil
+	}
+	return TriggerRule{}, fmt.Errorf("unknown trigger rule %q", rule)
+}
+
+// WithTriggerRules sets the trigger rules of the tasks, the tasks without rule use all_success
+func WithTriggerRules(rules map[string]TriggerRule) Option {
+	return func(graph *acyclicGraph) {
+		graph.triggerRules = rules
+	}
+}
+
+// satisfied reports whether the task runs, the blocked dependencies are never done so the task never runs
+func (r TriggerRule) satisfied(result depsResult) bool {
+	if result.blocked > 0 {
+		return false
+	}
+	switch r.Name {
+	case starriver.TriggerRuleAllDone:
+		return true
+	case starriver.TriggerRuleOneSuccess:
+		return result.success > 0
+	case starriver.TriggerRuleOneFailed:
+		return result.failed > 0
+	case starriver.TriggerRuleNoneFailed:
+		return result.failed == 0
+	case starriver.TriggerRuleNoneFailedMinOneSuccess:
+		return result.failed == 0 && result.success > 0
+	case starriver.TriggerRuleAtLeast:
+		return result.success >= r.N
+	default:
+		return result.failed == 0
+	}
+}
+
+// tolerant reports whether the failed dependencies are expected when the rule is satisfied, like the @any node,
+// so they are changed to warning and do not fail the pipeline.
+func (r TriggerRule) tolerant() bool {
+	return r.Name == starriver.TriggerRuleOneSuccess || r.Name == starriver.TriggerRuleAtLeast
+}
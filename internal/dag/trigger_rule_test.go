@@ -0,0 +1,51 @@
+package dag
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/thanksloving/starriver"
+)
+
+func TestParseTriggerRule(t *testing.T) {
+	rule, err := ParseTriggerRule("")
+	assert.NoError(t, err)
+	assert.Equal(t, TriggerRule{Name: starriver.TriggerRuleAllSuccess}, rule)
+	rule, err = ParseTriggerRule("none_failed_min_one_success")
+	assert.NoError(t, err)
+	assert.Equal(t, TriggerRule{Name: starriver.TriggerRuleNoneFailedMinOneSuccess}, rule)
+	rule, err = ParseTriggerRule("at_least(2)")
+	assert.NoError(t, err)
+	assert.Equal(t, TriggerRule{Name: starriver.TriggerRuleAtLeast, N: 2}, rule)
+
+	for _, invalid := range []starriver.TriggerRule{"at_least", "at_least(0)", "at_least(-1)", "at_least(a)", "all"} {
+		_, err = ParseTriggerRule(invalid)
+		assert.Error(t, err, invalid)
+	}
+}
+
+func TestTriggerRule_Satisfied(t *testing.T) {
+	results := map[string]depsResult{
+		"success": {success: 2},
+		"failed":  {success: 1, failed: 1},
+		"skipped": {skipped: 2},
+		"mixed":   {success: 1, skipped: 1},
+		"blocked": {success: 1, blocked: 1},
+	}
+	for rule, expected := range map[starriver.TriggerRule]map[string]bool{
+		"all_success":                 {"success": true, "failed": false, "skipped": true, "mixed": true, "blocked": false},
+		"all_done":                    {"success": true, "failed": true, "skipped": true, "mixed": true, "blocked": false},
+		"one_success":                 {"success": true, "failed": true, "skipped": false, "mixed": true, "blocked": false},
+		"one_failed":                  {"success": false, "failed": true, "skipped": false, "mixed": false, "blocked": false},
+		"none_failed":                 {"success": true, "failed": false, "skipped": true, "mixed": true, "blocked": false},
+		"none_failed_min_one_success": {"success": true, "failed": false, "skipped": false, "mixed": true, "blocked": false},
+		"at_least(2)":                 {"success": true, "failed": false, "skipped": false, "mixed": false, "blocked": false},
+	} {
+		r, err := ParseTriggerRule(rule)
+		assert.NoError(t, err)
+		for name, result := range results {
+			assert.Equal(t, expected[name], r.satisfied(result), "%s %s", rule, name)
+		}
+	}
+}
@@ -2,7 +2,9 @@ package dag
 
 import (
 	"fmt"
+	"sort"
 	"sync"
+	"time"
 
 	"github.com/thanksloving/starriver"
 	"github.com/thanksloving/starriver/helper"
@@ -43,6 +45,10 @@ type Walker struct {
 	// When false (default), the target depends on the source.
 	Reverse bool
 
+	// TriggerRules decides whether the vertex runs by the results of its
+	// dependencies, the vertices without rule use all_success.
+	TriggerRules map[string]TriggerRule
+
 	// changeLock must be held to modify any of the fields below. Only Update
 	// should modify these fields. Modifying them outside of Update can cause
 	// serious problems.
@@ -58,11 +64,14 @@ type Walker struct {
 	// respMap contains the diagnostics recorded so far for execution,
 	// and upstreamFailed contains all the vertices whose problems were
 	// caused by upstream failures, and thus whose diagnostics should be
-	// excluded from the final set.
+	// excluded from the final set. skipped contains the vertices not taken
+	// by routing, their descendants are skipped rather than failed.
 	//
-	// Readers and writers of either map must hold respLock.
+	// Readers and writers of the maps must hold respLock.
 	respMap        map[string]starriver.Response
 	upstreamFailed map[string]struct{}
+	skipped        map[string]struct{}
+	records        map[string]*starriver.TaskRecord
 	respLock       sync.Mutex
 }
 
@@ -75,6 +84,26 @@ func (w *Walker) init() {
 	}
 }
 
+// depsState is the result of the dependencies of a vertex
+type depsState int
+
+const (
+	depsSatisfied depsState = iota // the vertex runs
+	depsFailed                     // an upstream failed or the walk was cancelled
+	depsSkipped                    // the upstreams were skipped by routing, so the vertex is skipped too
+)
+
+func (s depsState) String() string {
+	switch s {
+	case depsSatisfied:
+		return "satisfied"
+	case depsSkipped:
+		return "skipped"
+	default:
+		return "failed"
+	}
+}
+
 type walkerVertex struct {
 	// These should only be set once on initialization and never written again.
 	// They are not protected by a lock since they don't need to be since
@@ -95,11 +124,11 @@ type walkerVertex struct {
 	// holding DepsLock.
 	//
 	// DepsCh is sent a single value that denotes whether the upstream deps
-	// were successful (no errors). Any value sent means that the upstream
-	// dependencies are complete. No other values will ever be sent again.
+	// were successful (no errors) or skipped. Any value sent means that the
+	// upstream dependencies are complete. No other values will ever be sent again.
 	//
 	// DepsUpdateCh is closed when there is a new DepsCh set.
-	DepsCh       chan bool
+	DepsCh       chan depsState
 	DepsUpdateCh chan struct{}
 	DepsLock     sync.Mutex
 
@@ -134,6 +163,23 @@ func (w *Walker) Wait() starriver.Responses {
 	return responses
 }
 
+// Records returns the records of the walked vertices, ordered by the ready time
+func (w *Walker) Records() []starriver.TaskRecord {
+	w.respLock.Lock()
+	defer w.respLock.Unlock()
+	records := make([]starriver.TaskRecord, 0, len(w.records))
+	for _, record := range w.records {
+		records = append(records, *record)
+	}
+	sort.Slice(records, func(i, j int) bool {
+		if records[i].ReadyAt.Equal(records[j].ReadyAt) {
+			return records[i].TaskID < records[j].TaskID
+		}
+		return records[i].ReadyAt.Before(records[j].ReadyAt)
+	})
+	return records
+}
+
 func graphObjListToSet(list interface{}) Set {
 	result := make(Set)
 	switch ls := list.(type) {
@@ -295,7 +341,7 @@ func (w *Walker) Update(g DAG) {
 		}
 
 		// Create a new done channel
-		doneCh := make(chan bool, 1)
+		doneCh := make(chan depsState, 1)
 
 		// Create the channel we close for cancellation
 		cancelCh := make(chan struct{})
@@ -354,10 +400,10 @@ func (w *Walker) walkVertex(v Vertex, dataContext starriver.DataContext, info *w
 
 	// Wait for our dependencies. We create a [closed] deps channel so
 	// that we can immediately fall through to load our actual DepsCh.
-	var depsSuccess bool
+	var deps depsState
 	var depsUpdateCh chan struct{}
-	depsCh := make(chan bool, 1)
-	depsCh <- true
+	depsCh := make(chan depsState, 1)
+	depsCh <- depsSatisfied
 	close(depsCh)
 	for {
 		select {
@@ -365,7 +411,7 @@ func (w *Walker) walkVertex(v Vertex, dataContext starriver.DataContext, info *w
 			// Cancel
 			return
 
-		case depsSuccess = <-depsCh:
+		case deps = <-depsCh:
 			// Deps complete! Mark as nil to trigger completion handling.
 			depsCh = nil
 
@@ -404,9 +450,21 @@ func (w *Walker) walkVertex(v Vertex, dataContext starriver.DataContext, info *w
 
 	// Run our callback or note that our upstream failed
 	var response starriver.Response
-	var upstreamFailed bool
+	var upstreamFailed, skipped bool
+	record := &starriver.TaskRecord{TaskID: v.ID(), ReadyAt: time.Now()}
+	events := starriver.TaskEventHandlerFromContext(dataContext.Context())
 	taskConfig := dataContext.Pipeline().GetTaskConfigure(v.ID())
-	if depsSuccess {
+	ctx, span := starriver.StartSpan(dataContext.Context(), "task "+v.ID(), taskAttributes(dataContext.Pipeline(), v.ID())...)
+	defer func() {
+		span.SetAttributes(starriver.Attr(starriver.AttrTaskStatus, string(dataContext.Pipeline().GetTaskStatus(v.ID()))))
+		if response != nil {
+			span.SetAttributes(starriver.Attr(starriver.AttrFailureLevel, int64(response.GetFailureLevel())))
+			span.RecordError(response.GetError())
+		}
+		span.End()
+	}()
+	if deps == depsSatisfied {
+		events.OnTaskReady(dataContext, v.ID())
 		properties := make(map[string]interface{})
 		dependNodes := make([]string, len(info.UpEdges))
 		for idx, upEdge := range info.UpEdges {
@@ -415,17 +473,30 @@ func (w *Walker) walkVertex(v Vertex, dataContext starriver.DataContext, info *w
 				properties[key] = val
 			}
 		}
-		newDataContext, cancel := newNodeDataContext(dataContext, taskConfig.Timeout)
+		newDataContext, cancel := newNodeDataContext(ctx, dataContext, taskConfig.Timeout)
 		if cancel != nil {
 			defer cancel()
 		}
 		for _, upEdge := range info.UpEdges {
 			newDataContext.AppendPrevTask(upEdge.Source().ID())
 			newDataContext.AppendProperties(upEdge.Properties())
+			if be, ok := upEdge.(*branchEdge); ok && !be.Match(newDataContext) {
+				// the switch node selected another branch
+				upstreamFailed, skipped = true, true
+				dataContext.Pipeline().SetTaskStatus(v.ID(), starriver.TaskStatusSkipped)
+				response = helper.NewSkippedResponse(fmt.Errorf("branch %q not taken", be.branch))
+				record.SkipReason = starriver.SkipReasonBranchNotTaken
+				events.OnTaskSkipped(dataContext, v.ID(), record.SkipReason)
+				break
+			}
 			if ce, ok := upEdge.(IsConditionalEdge); ok && !ce.Match(newDataContext) {
 				// condition not match
-				upstreamFailed = true
-				response = helper.NewWarnResponse(fmt.Errorf("condition not match"))
+				upstreamFailed, skipped = true, true
+				dataContext.Pipeline().SetTaskStatus(v.ID(), starriver.TaskStatusSkipped)
+				response = helper.NewSkippedResponse(fmt.Errorf("condition not match"))
+				record.SkipReason = starriver.SkipReasonConditionNotMatch
+				record.FailedCondition = &starriver.FailedCondition{Depend: upEdge.Source().ID(), Condition: ce.Condition()}
+				events.OnTaskSkipped(dataContext, v.ID(), record.SkipReason)
 				break
 			}
 		}
@@ -433,15 +504,24 @@ func (w *Walker) walkVertex(v Vertex, dataContext starriver.DataContext, info *w
 			if taskConfig.SkipExecution {
 				dataContext.Pipeline().SetTaskStatus(v.ID(), starriver.TaskStatusSkipped)
 				response = helper.NewSuccessResponse()
+				record.SkipReason = starriver.SkipReasonSkipExecution
+				events.OnTaskSkipped(dataContext, v.ID(), record.SkipReason)
+			} else if isDone(dataContext.Pipeline().GetTaskStatus(v.ID())) {
+				// the task was done before the pipeline is resumed, it is neither executed nor reported again
+				response = w.Callback(newDataContext, v)
 			} else {
+				record.StartedAt = time.Now()
+				events.OnTaskStart(dataContext, v.ID())
 				response = w.Callback(newDataContext, v)
 				dataContext.Pipeline().SetTaskStatus(v.ID(), response.GetStatus())
+				events.OnTaskEnd(dataContext, v.ID(), response, time.Since(record.StartedAt))
 				if data := response.GetData(); len(data) > 0 {
 					newDataContext.SetCurrentNodeData(v.ID(), data)
 				}
 
 				if response.GetStatus() == starriver.TaskStatusBlocked {
 					upstreamFailed = true
+					events.OnTaskBlocked(dataContext, v.ID(), response)
 				} else if taskConfig.AlwaysPass {
 					upstreamFailed = false
 				}
@@ -451,10 +531,25 @@ func (w *Walker) walkVertex(v Vertex, dataContext starriver.DataContext, info *w
 				}
 			}
 		}
+	} else if deps == depsSkipped {
+		dataContext.Debugf("[TRACE] dag/walk: upstream of %q skipped, so skipping", v.ID())
+		upstreamFailed, skipped = true, true
+		dataContext.Pipeline().SetTaskStatus(v.ID(), starriver.TaskStatusSkipped)
+		response = helper.NewSkippedResponse(fmt.Errorf("upstream is skipped"))
+		record.SkipReason = starriver.SkipReasonUpstreamSkipped
+		events.OnTaskSkipped(dataContext, v.ID(), record.SkipReason)
 	} else {
 		dataContext.Debugf("[TRACE] dag/walk: upstream of %q errored, so skipping", v.ID())
 		upstreamFailed = true
 		response = helper.NewWarnResponse(fmt.Errorf("upstream is failure"))
+		record.SkipReason = starriver.SkipReasonUpstreamFailed
+		events.OnTaskSkipped(dataContext, v.ID(), record.SkipReason)
+	}
+	record.EndedAt = time.Now()
+	record.Status = dataContext.Pipeline().GetTaskStatus(v.ID())
+	record.FailureLevel = response.GetFailureLevel()
+	if err := response.GetError(); err != nil {
+		record.Error = err.Error()
 	}
 
 	// Record the result (we must do this after execution because we mustn't
@@ -470,14 +565,52 @@ func (w *Walker) walkVertex(v Vertex, dataContext starriver.DataContext, info *w
 	if upstreamFailed {
 		w.upstreamFailed[v.ID()] = struct{}{}
 	}
+	if w.skipped == nil {
+		w.skipped = make(map[string]struct{})
+	}
+	if skipped {
+		w.skipped[v.ID()] = struct{}{}
+	}
+	if w.records == nil {
+		w.records = make(map[string]*starriver.TaskRecord)
+	}
+	w.records[v.ID()] = record
 	w.respLock.Unlock()
 }
 
+// isDone reports whether the task status is final
+func isDone(status starriver.TaskStatus) bool {
+	switch status {
+	case starriver.TaskStatusSuccess, starriver.TaskStatusSkipped, starriver.TaskStatusFailure:
+		return true
+	}
+	return false
+}
+
+// taskAttributes returns the attributes of the task span, the component is located by the pipeline configure
+func taskAttributes(pipeline starriver.Pipeline, taskID string) []starriver.Attribute {
+	attributes := []starriver.Attribute{starriver.Attr(starriver.AttrTaskID, taskID)}
+	withConf, ok := pipeline.(interface{ GetConf() starriver.PipelineConf })
+	if !ok {
+		return attributes
+	}
+	for _, task := range withConf.GetConf().Pipeline {
+		if task.ID == taskID {
+			attributes = append(attributes, starriver.Attr(starriver.AttrComponent, task.Name))
+			if task.Namespace != nil {
+				attributes = append(attributes, starriver.Attr(starriver.AttrNamespace, *task.Namespace))
+			}
+			break
+		}
+	}
+	return attributes
+}
+
 func (w *Walker) waitDeps(
 	dataContext starriver.DataContext,
 	v Vertex,
 	deps map[Vertex]<-chan struct{},
-	doneCh chan<- bool,
+	doneCh chan<- depsState,
 	cancelCh <-chan struct{}) {
 
 	// For each dependency given to us, wait for it to complete
@@ -487,7 +620,7 @@ func (w *Walker) waitDeps(
 			select {
 			case <-dataContext.Done():
 				// Context cancelled/timeout
-				doneCh <- false
+				doneCh <- depsFailed
 				return
 			case <-depCh:
 				// Dependency satisfied!
@@ -497,7 +630,7 @@ func (w *Walker) waitDeps(
 			case <-cancelCh:
 				// Wait cancelled. Note that we didn't satisfy dependencies
 				// so that anything waiting on us also doesn't run.
-				doneCh <- false
+				doneCh <- depsFailed
 				return
 			}
 		}
@@ -505,45 +638,103 @@ func (w *Walker) waitDeps(
 	// Dependencies satisfied! We need to check if any errored
 	w.respLock.Lock()
 	defer w.respLock.Unlock()
+	if rule, ok := w.TriggerRules[v.ID()]; ok && rule.Name != starriver.TriggerRuleAllSuccess {
+		state := w.triggered(dataContext, rule, deps)
+		doneCh <- state
+		dataContext.Debugf("[TRACE] dag/walk: trigger rule %s(%d) result is %s -> %q", rule.Name, rule.N, state, v.ID())
+		return
+	}
 	// 判断依赖的节点的状态
-	var finalRes *struct{ pass bool }
+	var passed, failed, skipped bool
+	n, isNode := v.(Node)
+	isNode = isNode && n.GetType() != NodeTypeSwitch
 	for dep := range deps {
-		if resp := w.respMap[dep.ID()]; resp != nil {
-			if n, ok := v.(Node); ok {
-				switch n.GetType() {
-				case NodeTypeAny:
-					if resp.IsPass() {
-						finalRes = &struct{ pass bool }{true}
-						dataContext.Debugf("[TRACE] dag/walk: dependencies %q satisfied for AnyNode-> %q", dep.ID(), v.ID())
-					} else {
-						if finalRes == nil {
-							finalRes = &struct{ pass bool }{false}
-						}
-						resp.SetFailureLevel(starriver.FailureLevelWarning)
-						dataContext.Debugf("[TRACE] dag/walk: dependencies %q not satisfied for AnyNode, change to warning-> %q", dep.ID(), v.ID())
-					}
-				case NodeTypeNot:
-					if resp.IsPass() {
-						finalRes = &struct{ pass bool }{false}
-					} else {
-						finalRes = &struct{ pass bool }{true}
-						resp.SetFailureLevel(starriver.FailureLevelWarning)
-					}
+		resp := w.respMap[dep.ID()]
+		if resp == nil {
+			continue
+		}
+		if _, ok := w.skipped[dep.ID()]; ok {
+			skipped = true
+			continue
+		}
+		if isNode {
+			switch n.GetType() {
+			case NodeTypeAny:
+				if resp.IsPass() {
+					passed = true
+					dataContext.Debugf("[TRACE] dag/walk: dependencies %q satisfied for AnyNode-> %q", dep.ID(), v.ID())
+				} else {
+					failed = true
+					resp.SetFailureLevel(starriver.FailureLevelWarning)
+					dataContext.Debugf("[TRACE] dag/walk: dependencies %q not satisfied for AnyNode, change to warning-> %q", dep.ID(), v.ID())
+				}
+			case NodeTypeNot:
+				if resp.IsPass() {
+					failed = true
+				} else {
+					passed = true
+					resp.SetFailureLevel(starriver.FailureLevelWarning)
 				}
-				continue
-			}
-			if !resp.IsPass() {
-				dataContext.Debugf("[TRACE] dag/walk: dependencies %q not satisfied -> %q", dep.ID(), v.ID())
-				finalRes = &struct{ pass bool }{false}
-				break
 			}
+			continue
+		}
+		if !resp.IsPass() {
+			dataContext.Debugf("[TRACE] dag/walk: dependencies %q not satisfied -> %q", dep.ID(), v.ID())
+			failed = true
+			break
+		}
+	}
+	state := depsSatisfied
+	switch {
+	case isNode && passed:
+		// the @any node runs when one dependency passed, the @not node when its dependency failed
+	case failed:
+		state = depsFailed
+	case skipped:
+		state = depsSkipped
+	}
+	doneCh <- state
+	dataContext.Debugf("[TRACE] dag/walk: all dependencies result is %s -> %q", state, v.ID())
+}
+
+// triggered checks the results of the dependencies by the trigger rule, respLock must be held
+func (w *Walker) triggered(dataContext starriver.DataContext, rule TriggerRule, deps map[Vertex]<-chan struct{}) depsState {
+	var (
+		result depsResult
+		failed []starriver.Response
+	)
+	for dep := range deps {
+		resp := w.respMap[dep.ID()]
+		_, upstreamFailed := w.upstreamFailed[dep.ID()]
+		_, skipped := w.skipped[dep.ID()]
+		switch {
+		case resp == nil:
+			result.failed++
+		case resp.GetStatus() == starriver.TaskStatusBlocked:
+			result.blocked++
+		case skipped || dataContext.Pipeline().GetTaskStatus(dep.ID()) == starriver.TaskStatusSkipped:
+			result.skipped++
+		case upstreamFailed:
+			result.failed++
+		case resp.IsPass():
+			result.success++
+		default:
+			result.failed++
+			failed = append(failed, resp)
 		}
 	}
-	if finalRes == nil {
-		finalRes = &struct{ pass bool }{true}
+	if !rule.satisfied(result) {
+		if result.failed == 0 && result.blocked == 0 && result.skipped > 0 {
+			return depsSkipped
+		}
+		return depsFailed
+	}
+	if rule.tolerant() {
+		for _, resp := range failed {
+			resp.SetFailureLevel(starriver.FailureLevelWarning)
+		}
 	}
-	doneCh <- finalRes.pass
-	dataContext.Debugf("[TRACE] dag/walk: all dependencies result is %t -> %q", finalRes.pass, v.ID())
+	return depsSatisfied
 }
 
 //func (w *Walker) node(nodeType NodeType, v Vertex,
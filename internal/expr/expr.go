@@ -0,0 +1,440 @@
+// Package expr implements the boolean expression used by the conditions of edges, e.g.
+//
+//	user_level in ["C4","C5"] && score >= 0.8 || env.app == "demo"
+//
+// Supported operators (from low to high precedence) are ||, &&, !, and the comparisons
+// ==, !=, >, <, >=, <=, in. Operands may be numbers, strings, true, false, nil, lists and variables.
+package expr
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/spf13/cast"
+)
+
+type (
+	// Resolver returns the value of a variable
+	Resolver func(name string) (interface{}, bool)
+
+	// Expression is a compiled expression, it's safe for concurrent use.
+	Expression struct {
+		source string
+		root   node
+	}
+
+	node interface {
+		eval(resolve Resolver) (interface{}, error)
+	}
+
+	literalNode struct {
+		value interface{}
+	}
+
+	variableNode struct {
+		name string
+	}
+
+	listNode struct {
+		items []node
+	}
+
+	notNode struct {
+		operand node
+	}
+
+	logicalNode struct {
+		operator    string
+		left, right node
+	}
+
+	compareNode struct {
+		operator    string
+		left, right node
+	}
+
+	parser struct {
+		tokens []token
+		pos    int
+	}
+)
+
+// Compile parses the expression
+func Compile(source string) (*Expression, error) {
+	tokens, err := tokenize(source)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens}
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.typ != tokenEOF {
+		return nil, fmt.Errorf("unexpected %q at %d", t.text, t.pos)
+	}
+	return &Expression{source: source, root: root}, nil
+}
+
+func (e *Expression) String() string {
+	return e.source
+}
+
+// Eval evaluates the expression and returns its value
+func (e *Expression) Eval(resolve Resolver) (interface{}, error) {
+	return e.root.eval(resolve)
+}
+
+// EvalBool evaluates the expression, the result must be a boolean
+func (e *Expression) EvalBool(resolve Resolver) (bool, error) {
+	val, err := e.root.eval(resolve)
+	if err != nil {
+		return false, err
+	}
+	return toBool(val)
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.typ != tokenEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *parser) isOperator(ops ...string) (string, bool) {
+	t := p.peek()
+	if t.typ != tokenOperator && !(t.typ == tokenIdent && t.text == "in") {
+		return "", false
+	}
+	for _, op := range ops {
+		if t.text == op {
+			return op, true
+		}
+	}
+	return "", false
+}
+
+func (p *parser) parseOr() (node, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		if _, ok := p.isOperator("||"); !ok {
+			return left, nil
+		}
+		p.next()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &logicalNode{operator: "||", left: left, right: right}
+	}
+}
+
+func (p *parser) parseAnd() (node, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		if _, ok := p.isOperator("&&"); !ok {
+			return left, nil
+		}
+		p.next()
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		left = &logicalNode{operator: "&&", left: left, right: right}
+	}
+}
+
+func (p *parser) parseNot() (node, error) {
+	if _, ok := p.isOperator("!"); ok {
+		p.next()
+		operand, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return &notNode{operand: operand}, nil
+	}
+	return p.parseComparison()
+}
+
+func (p *parser) parseComparison() (node, error) {
+	left, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+	op, ok := p.isOperator("==", "!=", ">=", "<=", ">", "<", "in")
+	if !ok {
+		return left, nil
+	}
+	p.next()
+	right, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+	return &compareNode{operator: op, left: left, right: right}, nil
+}
+
+func (p *parser) parsePrimary() (node, error) {
+	t := p.next()
+	switch t.typ {
+	case tokenNumber, tokenString:
+		return &literalNode{value: t.value}, nil
+	case tokenIdent:
+		switch t.text {
+		case "true":
+			return &literalNode{value: true}, nil
+		case "false":
+			return &literalNode{value: false}, nil
+		case "nil", "null":
+			return &literalNode{value: nil}, nil
+		case "in":
+			return nil, fmt.Errorf("unexpected %q at %d", t.text, t.pos)
+		}
+		return &variableNode{name: t.text}, nil
+	case tokenLParen:
+		inner, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if closing := p.next(); closing.typ != tokenRParen {
+			return nil, fmt.Errorf("expect ')' at %d", closing.pos)
+		}
+		return inner, nil
+	case tokenLBracket:
+		list := &listNode{}
+		if p.peek().typ == tokenRBracket {
+			p.next()
+			return list, nil
+		}
+		for {
+			item, err := p.parsePrimary()
+			if err != nil {
+				return nil, err
+			}
+			list.items = append(list.items, item)
+			switch sep := p.next(); sep.typ {
+			case tokenComma:
+			case tokenRBracket:
+				return list, nil
+			default:
+				return nil, fmt.Errorf("expect ',' or ']' at %d", sep.pos)
+			}
+		}
+	case tokenEOF:
+		return nil, fmt.Errorf("unexpected end of expression")
+	}
+	return nil, fmt.Errorf("unexpected %q at %d", t.text, t.pos)
+}
+
+func (n *literalNode) eval(Resolver) (interface{}, error) {
+	return n.value, nil
+}
+
+// eval resolves the variable, a dotted name such as user.level will be looked up as a whole first,
+// then as the member of its prefix.
+func (n *variableNode) eval(resolve Resolver) (interface{}, error) {
+	if val, ok := resolve(n.name); ok {
+		return val, nil
+	}
+	parts := strings.Split(n.name, ".")
+	for i := len(parts) - 1; i > 0; i-- {
+		val, ok := resolve(strings.Join(parts[:i], "."))
+		if !ok {
+			continue
+		}
+		for _, member := range parts[i:] {
+			if val, ok = memberOf(val, member); !ok {
+				return nil, nil
+			}
+		}
+		return val, nil
+	}
+	return nil, nil
+}
+
+func (n *listNode) eval(resolve Resolver) (interface{}, error) {
+	list := make([]interface{}, len(n.items))
+	for idx, item := range n.items {
+		val, err := item.eval(resolve)
+		if err != nil {
+			return nil, err
+		}
+		list[idx] = val
+	}
+	return list, nil
+}
+
+func (n *notNode) eval(resolve Resolver) (interface{}, error) {
+	val, err := n.operand.eval(resolve)
+	if err != nil {
+		return nil, err
+	}
+	b, err := toBool(val)
+	return !b, err
+}
+
+func (n *logicalNode) eval(resolve Resolver) (interface{}, error) {
+	val, err := n.left.eval(resolve)
+	if err != nil {
+		return nil, err
+	}
+	left, err := toBool(val)
+	if err != nil {
+		return nil, err
+	}
+	if (n.operator == "||" && left) || (n.operator == "&&" && !left) {
+		return left, nil
+	}
+	if val, err = n.right.eval(resolve); err != nil {
+		return nil, err
+	}
+	return toBool(val)
+}
+
+func (n *compareNode) eval(resolve Resolver) (interface{}, error) {
+	left, err := n.left.eval(resolve)
+	if err != nil {
+		return nil, err
+	}
+	right, err := n.right.eval(resolve)
+	if err != nil {
+		return nil, err
+	}
+	switch n.operator {
+	case "==":
+		return equal(left, right), nil
+	case "!=":
+		return !equal(left, right), nil
+	case "in":
+		return contains(right, left)
+	}
+	return compare(n.operator, left, right)
+}
+
+func memberOf(val interface{}, member string) (interface{}, bool) {
+	v := reflect.ValueOf(val)
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		v = v.Elem()
+	}
+	switch v.Kind() {
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String {
+			return nil, false
+		}
+		item := v.MapIndex(reflect.ValueOf(member).Convert(v.Type().Key()))
+		if !item.IsValid() {
+			return nil, false
+		}
+		return item.Interface(), true
+	case reflect.Struct:
+		field := v.FieldByName(member)
+		if !field.IsValid() || !field.CanInterface() {
+			return nil, false
+		}
+		return field.Interface(), true
+	}
+	return nil, false
+}
+
+func toBool(val interface{}) (bool, error) {
+	switch b := val.(type) {
+	case nil:
+		return false, nil
+	case bool:
+		return b, nil
+	}
+	return cast.ToBoolE(val)
+}
+
+func isNumber(val interface{}) bool {
+	switch reflect.ValueOf(val).Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
+
+// equal compares numbers by value regardless of their types, others by reflect.DeepEqual
+func equal(left, right interface{}) bool {
+	if isNumber(left) && isNumber(right) {
+		return cast.ToFloat64(left) == cast.ToFloat64(right)
+	}
+	return reflect.DeepEqual(left, right)
+}
+
+func contains(collection, val interface{}) (bool, error) {
+	if s, ok := collection.(string); ok {
+		sub, ok := val.(string)
+		return ok && strings.Contains(s, sub), nil
+	}
+	v := reflect.ValueOf(collection)
+	switch v.Kind() {
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			if equal(val, v.Index(i).Interface()) {
+				return true, nil
+			}
+		}
+		return false, nil
+	case reflect.Map:
+		for _, key := range v.MapKeys() {
+			if equal(val, key.Interface()) {
+				return true, nil
+			}
+		}
+		return false, nil
+	case reflect.Invalid:
+		return false, nil
+	}
+	return false, fmt.Errorf("'in' does not support %T", collection)
+}
+
+func compare(operator string, left, right interface{}) (bool, error) {
+	var result int
+	if ls, ok := left.(string); ok {
+		rs, ok := right.(string)
+		if !ok {
+			return false, fmt.Errorf("can not compare %T with %T", left, right)
+		}
+		result = strings.Compare(ls, rs)
+	} else {
+		l, err := cast.ToFloat64E(left)
+		if err != nil || left == nil {
+			return false, fmt.Errorf("can not compare %T with %T", left, right)
+		}
+		r, err := cast.ToFloat64E(right)
+		if err != nil || right == nil {
+			return false, fmt.Errorf("can not compare %T with %T", left, right)
+		}
+		switch {
+		case l < r:
+			result = -1
+		case l > r:
+			result = 1
+		}
+	}
+	switch operator {
+	case ">":
+		return result > 0, nil
+	case "<":
+		return result < 0, nil
+	case ">=":
+		return result >= 0, nil
+	case "<=":
+		return result <= 0, nil
+	}
+	return false, fmt.Errorf("%q not support", operator)
+}
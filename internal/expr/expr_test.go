@@ -0,0 +1,75 @@
+package expr
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEvalBool(t *testing.T) {
+	data := map[string]interface{}{
+		"user_level": "C4",
+		"score":      0.9,
+		"age":        18,
+		"tags":       []string{"a", "b"},
+		"user":       map[string]interface{}{"name": "jimmy", "vip": true},
+		"env.app":    "demo",
+		"用户.等级":      "黄金",
+	}
+	resolve := func(name string) (interface{}, bool) {
+		val, ok := data[name]
+		return val, ok
+	}
+	tests := []struct {
+		expr   string
+		result bool
+	}{
+		{`user_level in ["C4","C5"] && score >= 0.8 || env.app == "demo"`, true},
+		{`user_level in ["C4","C5"] && score >= 0.95`, false},
+		{`user_level in ["C4","C5"] && score >= 0.95 || env.app == "demo"`, true},
+		{`age == 18 && age > 17.5 && age != 19`, true},
+		{`age < -1`, false},
+		{`!(age >= 18)`, false},
+		{`"a" in tags && !("c" in tags)`, true},
+		{`user.name == 'jimmy' && user.vip`, true},
+		{`user.unknown == nil && not_exist == nil`, true},
+		{`not_exist`, false},
+		{`user_level > "C3"`, true},
+		{`"ji" in user.name`, true},
+		{`true && (false || true)`, true},
+		{`用户.等级 == "黄金" && user_level in ['C4', "会员"]`, true},
+		{`"金" in 用户.等级`, true},
+	}
+	for _, test := range tests {
+		e, err := Compile(test.expr)
+		assert.NoError(t, err, test.expr)
+		result, err := e.EvalBool(resolve)
+		assert.NoError(t, err, test.expr)
+		assert.Equal(t, test.result, result, test.expr)
+	}
+}
+
+func TestEvalError(t *testing.T) {
+	e, err := Compile(`user_level > 1`)
+	assert.NoError(t, err)
+	_, err = e.EvalBool(func(string) (interface{}, bool) { return "C4", true })
+	assert.Error(t, err)
+}
+
+func TestCompileError(t *testing.T) {
+	for _, source := range []string{
+		``,
+		`a ==`,
+		`a == "b`,
+		`(a == 1`,
+		`a in [1, 2`,
+		`a == 1 b`,
+		`a # 1`,
+		`in == 1`,
+		`a == "会员`,
+		"a == \xff",
+	} {
+		_, err := Compile(source)
+		assert.Error(t, err, source)
+	}
+}
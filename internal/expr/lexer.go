@@ -0,0 +1,135 @@
+package expr
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	tokenEOF tokenType = iota
+	tokenIdent
+	tokenNumber
+	tokenString
+	tokenOperator
+	tokenLParen
+	tokenRParen
+	tokenLBracket
+	tokenRBracket
+	tokenComma
+)
+
+type (
+	tokenType int
+
+	token struct {
+		typ   tokenType
+		text  string
+		value interface{}
+		pos   int
+	}
+)
+
+var operators = []string{"&&", "||", "==", "!=", ">=", "<=", ">", "<", "!"}
+
+// tokenize splits the expression into tokens, the last one is always tokenEOF, the positions are byte offsets
+func tokenize(input string) ([]token, error) {
+	var tokens []token
+	for pos := 0; pos < len(input); {
+		c, size := utf8.DecodeRuneInString(input[pos:])
+		switch {
+		case c == utf8.RuneError && size == 1:
+			return nil, fmt.Errorf("invalid utf-8 character at %d", pos)
+		case unicode.IsSpace(c):
+			pos += size
+		case c == '(':
+			tokens = append(tokens, token{typ: tokenLParen, text: "(", pos: pos})
+			pos++
+		case c == ')':
+			tokens = append(tokens, token{typ: tokenRParen, text: ")", pos: pos})
+			pos++
+		case c == '[':
+			tokens = append(tokens, token{typ: tokenLBracket, text: "[", pos: pos})
+			pos++
+		case c == ']':
+			tokens = append(tokens, token{typ: tokenRBracket, text: "]", pos: pos})
+			pos++
+		case c == ',':
+			tokens = append(tokens, token{typ: tokenComma, text: ",", pos: pos})
+			pos++
+		case c == '"' || c == '\'':
+			end := pos + size
+			for end < len(input) {
+				r, n := utf8.DecodeRuneInString(input[end:])
+				if r == c {
+					break
+				}
+				end += n
+				if r == '\\' && end < len(input) {
+					_, n = utf8.DecodeRuneInString(input[end:])
+					end += n
+				}
+			}
+			if end >= len(input) {
+				return nil, fmt.Errorf("unterminated string at %d", pos)
+			}
+			text := input[pos : end+1]
+			value, err := unquote(text)
+			if err != nil {
+				return nil, fmt.Errorf("invalid string %s at %d: %v", text, pos, err)
+			}
+			tokens = append(tokens, token{typ: tokenString, text: text, value: value, pos: pos})
+			pos = end + 1
+		case unicode.IsDigit(c) || (c == '-' && pos+1 < len(input) && unicode.IsDigit(rune(input[pos+1]))):
+			end := pos + size
+			for end < len(input) {
+				r, n := utf8.DecodeRuneInString(input[end:])
+				if !unicode.IsDigit(r) && r != '.' {
+					break
+				}
+				end += n
+			}
+			text := input[pos:end]
+			value, err := strconv.ParseFloat(text, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid number %q at %d", text, pos)
+			}
+			tokens = append(tokens, token{typ: tokenNumber, text: text, value: value, pos: pos})
+			pos = end
+		case c == '_' || unicode.IsLetter(c):
+			end := pos + size
+			for end < len(input) {
+				r, n := utf8.DecodeRuneInString(input[end:])
+				if r != '_' && r != '.' && !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+					break
+				}
+				end += n
+			}
+			tokens = append(tokens, token{typ: tokenIdent, text: input[pos:end], pos: pos})
+			pos = end
+		default:
+			var matched bool
+			for _, op := range operators {
+				if strings.HasPrefix(input[pos:], op) {
+					tokens = append(tokens, token{typ: tokenOperator, text: op, pos: pos})
+					pos += len(op)
+					matched = true
+					break
+				}
+			}
+			if !matched {
+				return nil, fmt.Errorf("unexpected character %q at %d", c, pos)
+			}
+		}
+	}
+	return append(tokens, token{typ: tokenEOF, pos: len(input)}), nil
+}
+
+func unquote(text string) (string, error) {
+	if text[0] == '\'' {
+		text = `"` + strings.ReplaceAll(strings.ReplaceAll(text[1:len(text)-1], `\'`, `'`), `"`, `\"`) + `"`
+	}
+	return strconv.Unquote(text)
+}
@@ -0,0 +1,31 @@
+package starriver
+
+import "time"
+
+type (
+	// RunJournal records the runs of the engine, so the unfinished runs can be resumed after the process restarted.
+	RunJournal interface {
+		// Start records a run is started or resumed, the status and outputs of the tasks recorded before are kept
+		Start(run RunRecord) error
+		// Task records the status transition and the output of a task
+		Task(requestID, taskID string, status TaskStatus, data map[string]interface{}) error
+		// End records a run is ended, the snapshot is kept for the blocked run to be resumed later
+		End(requestID string, status PipelineStatus, snapshot []byte) error
+		// Get returns the run, nil if not found
+		Get(requestID string) (*RunRecord, error)
+		// Unfinished lists the runs started but never ended, e.g. the process crashed during the run
+		Unfinished() ([]*RunRecord, error)
+	}
+
+	RunRecord struct {
+		RequestID string                            `json:"request_id" yaml:"request_id" msgpack:"request_id"`
+		Conf      PipelineConf                      `json:"conf" yaml:"conf" msgpack:"conf"`
+		Status    PipelineStatus                    `json:"status" yaml:"status" msgpack:"status"`
+		State     map[string]TaskStatus             `json:"state" yaml:"state" msgpack:"state"`
+		NodeData  map[string]map[string]interface{} `json:"node_data" yaml:"node_data" msgpack:"node_data"`
+		Snapshot  []byte                            `json:"snapshot" yaml:"snapshot" msgpack:"snapshot"` // the shared data when started, or when ended
+		Finished  bool                              `json:"finished" yaml:"finished" msgpack:"finished"`
+		StartedAt time.Time                         `json:"started_at" yaml:"started_at" msgpack:"started_at"`
+		EndedAt   time.Time                         `json:"ended_at" yaml:"ended_at" msgpack:"ended_at"`
+	}
+)
@@ -0,0 +1,49 @@
+package starriver
+
+import (
+	"context"
+	"time"
+)
+
+const (
+	SemaphoreEngine   = "engine"   // RiverEngine.Semaphore, limits the concurrent runs of the engine
+	SemaphorePipeline = "pipeline" // the parallel semaphore of a pipeline, limits the concurrent tasks of a run
+)
+
+type (
+	// MetricsCollector records the metrics of the runs, it must be safe for concurrent use
+	MetricsCollector interface {
+		// ObservePipeline is called when a run of the pipeline is done
+		ObservePipeline(pipeline string, status PipelineStatus, duration time.Duration)
+		// ObserveTask is called when a task is executed, the duration includes all the attempts
+		ObserveTask(pipeline, taskID, component string, status TaskStatus, level FailureLevel, duration time.Duration)
+		// ObserveSemaphoreWait is called when a semaphore is acquired
+		ObserveSemaphoreWait(pipeline, semaphore string, wait time.Duration)
+	}
+
+	noopMetricsCollector struct{}
+
+	metricsCollectorKey struct{}
+)
+
+var _ MetricsCollector = (*noopMetricsCollector)(nil)
+
+// ContextWithMetricsCollector returns a copy of ctx carrying the collector, the runs with the ctx are recorded to it
+func ContextWithMetricsCollector(ctx context.Context, collector MetricsCollector) context.Context {
+	return context.WithValue(ctx, metricsCollectorKey{}, collector)
+}
+
+// MetricsCollectorFromContext returns the collector of ctx, it's a no-op collector if ctx has no collector
+func MetricsCollectorFromContext(ctx context.Context) MetricsCollector {
+	if collector, ok := ctx.Value(metricsCollectorKey{}).(MetricsCollector); ok && collector != nil {
+		return collector
+	}
+	return noopMetricsCollector{}
+}
+
+func (noopMetricsCollector) ObservePipeline(string, PipelineStatus, time.Duration) {}
+
+func (noopMetricsCollector) ObserveTask(string, string, string, TaskStatus, FailureLevel, time.Duration) {
+}
+
+func (noopMetricsCollector) ObserveSemaphoreWait(string, string, time.Duration) {}
@@ -0,0 +1,25 @@
+package starriver
+
+import "time"
+
+// LatestVersion gets the last put version of the pipeline from the repository
+const LatestVersion = "latest"
+
+type (
+	// PipelineRepository stores the named and versioned pipelines, a version can not be changed once it's put.
+	PipelineRepository interface {
+		// Put saves the pipeline as the version, the name and version of the conf are set to the given ones
+		Put(name, version string, conf PipelineConf) error
+		// Get returns the pipeline of the version, the last put one if the version is LatestVersion or empty, nil if not found
+		Get(name, version string) (*PipelineDefinition, error)
+		// List returns all the versions of all the pipelines, sorted by the name and then the put time
+		List() ([]PipelineDefinition, error)
+	}
+
+	PipelineDefinition struct {
+		Name      string       `json:"name" yaml:"name"`
+		Version   string       `json:"version" yaml:"version"`
+		Conf      PipelineConf `json:"conf" yaml:"conf"`
+		CreatedAt time.Time    `json:"created_at" yaml:"created_at"`
+	}
+)
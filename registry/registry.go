@@ -57,7 +57,7 @@ func init() {
 		defaultComponents: make(map[string]*starriver.Component),
 		customComponents:  make(map[string]map[string]*starriver.Component),
 	}
-	for _, nt := range []dag.NodeType{dag.NodeTypeNot, dag.NodeTypeAny} {
+	for _, nt := range []dag.NodeType{dag.NodeTypeNot, dag.NodeTypeAny, dag.NodeTypeSwitch} {
 		instance.builtinNodes[string(nt)] = func(nt dag.NodeType) nodeFunc {
 			return func(id string) starriver.Node {
 				return dag.NewNode(id, nt)
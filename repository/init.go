@@ -10,4 +10,6 @@ func init() {
 	registerChatGPT()
 	registerSubPipeline()
 	registerLoop()
+	registerMap()
+	registerWaitForSignal()
 }
@@ -19,8 +19,8 @@ type (
 	loopParam struct {
 		Items        []interface{}          `json:"items"`
 		PipelineConf starriver.PipelineConf `json:"pipeline_conf"`
-		ItemKey      string                 `json:"item_key"`       // the key to store the current item in the sub-pipeline's data context
-		IndexKey     string                 `json:"index_key"`      // the key to store the current index in the sub-pipeline's data context
+		ItemKey      string                 `json:"item_key"`  // the key to store the current item in the sub-pipeline's data context
+		IndexKey     string                 `json:"index_key"` // the key to store the current index in the sub-pipeline's data context
 		InputData    map[string]interface{} `json:"input_data"`
 		MaxLoop      int                    `json:"max_loop"`       // safeguard against infinite loops if items is too large
 		BreakOnError bool                   `json:"break_if_error"` // break loop if sub-pipeline fails
@@ -91,7 +91,7 @@ func (l *loopComponent) ParameterNew() interface{} {
 
 func (l *loopComponent) Execute(dataContext starriver.DataContext, param interface{}) starriver.Response {
 	p := param.(*loopParam)
-	
+
 	if p.ItemKey == "" {
 		p.ItemKey = "loop_item"
 	}
@@ -100,7 +100,7 @@ func (l *loopComponent) Execute(dataContext starriver.DataContext, param interfa
 	}
 
 	results := make([]map[string]interface{}, 0, len(p.Items))
-	
+
 	for i, item := range p.Items {
 		if p.MaxLoop > 0 && i >= p.MaxLoop {
 			dataContext.Warnf("loop reached max_loop limit: %d", p.MaxLoop)
@@ -127,10 +127,18 @@ func (l *loopComponent) Execute(dataContext starriver.DataContext, param interfa
 			return helper.NewErrorResponse(fmt.Errorf("build loop sub pipeline error at index %d: %v", i, err))
 		}
 
-		ctx := context.WithValue(dataContext.Context(), "X-B3-Traceid", fmt.Sprintf("%s-loop-%d", dataContext.GetRequestID(), i))
+		ctx, span := starriver.StartSpan(dataContext.Context(), fmt.Sprintf("loop %s[%d]", l.ID(), i),
+			starriver.Attr(starriver.AttrTaskID, l.ID()),
+			starriver.Attr(starriver.AttrPipelineName, subPipeline.GetName()),
+			starriver.Attr(starriver.AttrLoopIndex, i),
+		)
+		ctx = context.WithValue(ctx, "X-B3-Traceid", fmt.Sprintf("%s-loop-%d", dataContext.GetRequestID(), i))
 		subDataContext := core.NewDataContext(ctx, subPipeline, iterData)
-		
+
 		result := subPipeline.Run(subDataContext)
+		span.SetAttributes(starriver.Attr(starriver.AttrPipelineStatus, string(result.Status)))
+		span.RecordError(result.Error)
+		span.End()
 
 		if result.Status != starriver.PipelineStatusSuccess {
 			if p.BreakOnError {
@@ -21,7 +21,7 @@ func TestLoopComponent_Execute(t *testing.T) {
 	dc := core.NewDataContext(context.Background(), &mockPipeline{}, nil)
 
 	subConf := starriver.PipelineConf{
-		Name: "test_inner_loop",
+		Name:   "test_inner_loop",
 		Result: []string{"test_val"},
 		Pipeline: []starriver.Task{
 			{
@@ -30,8 +30,8 @@ func TestLoopComponent_Execute(t *testing.T) {
 				Config: starriver.TaskConfigure{
 					Params: []starriver.Param{
 						{
-							Name: "Pass",
-							Type: starriver.ParamTypeLiteral,
+							Name:    "Pass",
+							Type:    starriver.ParamTypeLiteral,
 							Literal: true,
 						},
 					},
@@ -43,18 +43,18 @@ func TestLoopComponent_Execute(t *testing.T) {
 				Config: starriver.TaskConfigure{
 					Params: []starriver.Param{
 						{
-							Name: "Template",
-							Type: starriver.ParamTypeLiteral,
+							Name:    "Template",
+							Type:    starriver.ParamTypeLiteral,
 							Literal: "hello loop",
 						},
 						{
-							Name: "OutputKey",
-							Type: starriver.ParamTypeLiteral,
+							Name:    "OutputKey",
+							Type:    starriver.ParamTypeLiteral,
 							Literal: "test_val",
 						},
 						{
-							Name: "Shared",
-							Type: starriver.ParamTypeLiteral,
+							Name:    "Shared",
+							Type:    starriver.ParamTypeLiteral,
 							Literal: true,
 						},
 					},
@@ -83,3 +83,48 @@ func TestLoopComponent_Execute(t *testing.T) {
 		assert.Equal(t, "hello loop", res["test_val"])
 	}
 }
+
+func TestLoopComponent_Tracing(t *testing.T) {
+	registerTestNode()
+	lc := &loopComponent{
+		SkeletonWithParameter: helper.NewSkeletonWithParameter("test_loop", &loopParam{}),
+	}
+	tracer := starriver.NewRecordingTracer()
+	ctx, span := starriver.StartSpan(starriver.ContextWithTracer(context.Background(), tracer), "task test_loop")
+	dc := core.NewDataContext(ctx, &mockPipeline{}, nil)
+
+	subConf := starriver.PipelineConf{
+		Name: "test_inner_loop",
+		Pipeline: []starriver.Task{
+			{
+				ID:   "inner",
+				Name: "TestNode",
+				Config: starriver.TaskConfigure{
+					Params: []starriver.Param{{Name: "Pass", Type: starriver.ParamTypeLiteral, Literal: true}},
+				},
+			},
+		},
+	}
+	resp := lc.Execute(dc, &loopParam{Items: []interface{}{1, 2}, PipelineConf: subConf})
+	span.End()
+	assert.True(t, resp.IsPass())
+
+	spans := tracer.Spans()
+	iterations := make(map[int]int)
+	for _, s := range spans {
+		if s.Name == "loop test_loop[0]" || s.Name == "loop test_loop[1]" {
+			assert.Equal(t, 1, s.ParentID)
+			assert.Equal(t, string(starriver.PipelineStatusSuccess), s.Attributes[starriver.AttrPipelineStatus])
+			iterations[s.ID] = s.Attributes[starriver.AttrLoopIndex].(int)
+		}
+	}
+	assert.Len(t, iterations, 2)
+	inner := 0
+	for _, s := range spans {
+		if s.Name == "task inner" {
+			assert.Contains(t, iterations, s.ParentID)
+			inner++
+		}
+	}
+	assert.Equal(t, 2, inner)
+}
@@ -0,0 +1,203 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sync"
+
+	"github.com/thanksloving/starriver"
+	"github.com/thanksloving/starriver/helper"
+	"github.com/thanksloving/starriver/internal/core"
+	"github.com/thanksloving/starriver/registry"
+)
+
+const (
+	mapDefaultParallel = 10 // how many sub-pipelines run at the same time when Parallel is not positive
+
+	mapFailFast      = "fail_fast"      // cancel the running items and fail once an item fails
+	mapCollectErrors = "collect_errors" // run all the items and output the errors
+)
+
+type (
+	mapComponent struct {
+		helper.SkeletonWithParameter
+	}
+
+	mapParam struct {
+		Items        []interface{}          `json:"items"`
+		PipelineConf starriver.PipelineConf `json:"pipeline_conf"`
+		ItemKey      string                 `json:"item_key"`   // the key to store the current item in the sub-pipeline's data context
+		IndexKey     string                 `json:"index_key"`  // the key to store the current index in the sub-pipeline's data context
+		InputData    map[string]interface{} `json:"input_data"` // shared by all the sub-pipelines
+		Parallel     int                    `json:"parallel"`   // how many sub-pipelines run at the same time
+		ErrorMode    string                 `json:"error_mode"` // fail_fast or collect_errors
+	}
+)
+
+var _ starriver.Executable = (*mapComponent)(nil)
+
+func registerMap() {
+	registry.Register("Map", "并行循环节点，对数组中的每个元素并发执行一个子流程，结果保持元素顺序",
+		func(id string) starriver.Executable {
+			return &mapComponent{helper.NewSkeletonWithParameter(id, &mapParam{})}
+		},
+		registry.Input([]starriver.InputParam{
+			{
+				Key:      "Items",
+				Required: true,
+				Desc:     "需要遍历的数组",
+				Type:     reflect.Slice,
+			},
+			{
+				Key:      "PipelineConf",
+				Required: true,
+				Desc:     "每个元素执行的子流程配置",
+			},
+			{
+				Key:      "ItemKey",
+				Required: false,
+				Desc:     "传递当前元素到子流程的变量名（默认: loop_item）",
+			},
+			{
+				Key:      "IndexKey",
+				Required: false,
+				Desc:     "传递当前索引到子流程的变量名（默认: loop_index）",
+			},
+			{
+				Key:      "InputData",
+				Required: false,
+				Desc:     "传递给每个子流程的公共初始数据",
+			},
+			{
+				Key:      "Parallel",
+				Required: false,
+				Desc:     "同时执行的子流程数（默认: 10）",
+			},
+			{
+				Key:      "ErrorMode",
+				Required: false,
+				Desc:     "子流程失败时的处理方式，fail_fast 取消其余子流程并失败，collect_errors 执行完所有子流程并输出错误（默认: fail_fast）",
+				Options:  []interface{}{mapFailFast, mapCollectErrors},
+			},
+		}),
+		registry.Output(map[string]starriver.OutputValue{
+			"Results": {
+				Desc: "所有子流程的执行结果数组，与元素顺序一致，失败的子流程为 nil",
+				Type: reflect.Slice,
+			},
+			"Errors": {
+				Desc: "所有子流程的错误数组，与元素顺序一致，成功的子流程为空字符串",
+				Type: reflect.Slice,
+			},
+		}),
+	)
+}
+
+func (m *mapComponent) ParameterNew() interface{} {
+	return &mapParam{
+		ItemKey:   "loop_item",
+		IndexKey:  "loop_index",
+		Parallel:  mapDefaultParallel,
+		ErrorMode: mapFailFast,
+	}
+}
+
+func (m *mapComponent) Execute(dataContext starriver.DataContext, param interface{}) starriver.Response {
+	p := param.(*mapParam)
+	if p.ItemKey == "" {
+		p.ItemKey = "loop_item"
+	}
+	if p.IndexKey == "" {
+		p.IndexKey = "loop_index"
+	}
+	if p.Parallel <= 0 {
+		p.Parallel = mapDefaultParallel
+	}
+	if p.ErrorMode != mapFailFast && p.ErrorMode != mapCollectErrors {
+		return helper.NewErrorResponse(fmt.Errorf("unknown error mode %q", p.ErrorMode))
+	}
+	if _, err := core.BuildPipeline(p.PipelineConf, starriver.PipelineStatusInit, make(map[string]starriver.TaskStatus)); err != nil {
+		return helper.NewErrorResponse(fmt.Errorf("build map sub pipeline error: %v", err))
+	}
+
+	// the items are cancelled together when the parent is stopped, or an item fails in fail_fast mode
+	ctx, cancel := context.WithCancel(dataContext.Context())
+	defer cancel()
+	var (
+		wg       sync.WaitGroup
+		once     sync.Once
+		firstErr error
+		sem      = make(chan struct{}, p.Parallel)
+		results  = make([]map[string]interface{}, len(p.Items))
+		errs     = make([]string, len(p.Items))
+	)
+Items:
+	for i, item := range p.Items {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			break Items
+		}
+		wg.Add(1)
+		go func(i int, item interface{}) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			result := m.runItem(ctx, dataContext, p, i, item)
+			if result.Status != starriver.PipelineStatusSuccess {
+				err := fmt.Errorf("map sub pipeline executed failed at index %d, status: %s, err: %v", i, result.Status, result.Error)
+				errs[i] = err.Error()
+				if p.ErrorMode == mapFailFast {
+					once.Do(func() {
+						firstErr = err
+						cancel()
+					})
+				} else {
+					dataContext.Warnf("%v, continuing...", err)
+				}
+				return
+			}
+			results[i] = result.Data
+		}(i, item)
+	}
+	wg.Wait()
+
+	if err := dataContext.Err(); err != nil {
+		return helper.NewErrorResponse(err)
+	}
+	if firstErr != nil {
+		return helper.NewErrorResponse(firstErr)
+	}
+	return helper.NewSuccessDataResponse(map[string]interface{}{
+		"Results": results,
+		"Errors":  errs,
+	})
+}
+
+// runItem runs the sub-pipeline of the item with ctx, so it's cancelled with ctx
+func (m *mapComponent) runItem(ctx context.Context, dataContext starriver.DataContext, p *mapParam, i int, item interface{}) starriver.Result {
+	iterData := make(map[string]interface{}, len(p.InputData)+2)
+	for k, v := range p.InputData {
+		iterData[k] = v
+	}
+	iterData[p.ItemKey] = item
+	iterData[p.IndexKey] = i
+
+	subPipeline, err := core.BuildPipeline(p.PipelineConf, starriver.PipelineStatusInit, make(map[string]starriver.TaskStatus))
+	if err != nil {
+		return starriver.Result{Status: starriver.PipelineStatusFailure, Error: err}
+	}
+	ctx, span := starriver.StartSpan(ctx, fmt.Sprintf("map %s[%d]", m.ID(), i),
+		starriver.Attr(starriver.AttrTaskID, m.ID()),
+		starriver.Attr(starriver.AttrPipelineName, subPipeline.GetName()),
+		starriver.Attr(starriver.AttrLoopIndex, i),
+	)
+	defer span.End()
+	ctx = context.WithValue(ctx, "X-B3-Traceid", fmt.Sprintf("%s-map-%d", dataContext.GetRequestID(), i))
+	result := subPipeline.Run(core.NewDataContext(ctx, subPipeline, iterData))
+	span.SetAttributes(starriver.Attr(starriver.AttrPipelineStatus, string(result.Status)))
+	span.RecordError(result.Error)
+	return result
+}
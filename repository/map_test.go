@@ -0,0 +1,154 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/thanksloving/starriver"
+	"github.com/thanksloving/starriver/helper"
+	"github.com/thanksloving/starriver/internal/core"
+)
+
+func newMapComponent() *mapComponent {
+	mc := &mapComponent{}
+	mc.SkeletonWithParameter = helper.NewSkeletonWithParameter("test_map", mc.ParameterNew())
+	return mc
+}
+
+// mapSubConf waits or checks the item by the first task, then outputs the item as "out"
+func mapSubConf(first starriver.Task) starriver.PipelineConf {
+	return starriver.PipelineConf{
+		Name:   "test_inner_map",
+		Result: []string{"out"},
+		Pipeline: []starriver.Task{
+			first,
+			{
+				ID:   "output",
+				Name: "Template",
+				Config: starriver.TaskConfigure{
+					Params: []starriver.Param{
+						{Name: "Template", Type: starriver.ParamTypeLiteral, Literal: `{{ str "loop_item" }}`},
+						{Name: "OutputKey", Type: starriver.ParamTypeLiteral, Literal: "out"},
+						{Name: "Shared", Type: starriver.ParamTypeLiteral, Literal: true},
+					},
+				},
+				Depends: []starriver.Depend{{ID: first.ID}},
+			},
+		},
+	}
+}
+
+func waitTask() starriver.Task {
+	return starriver.Task{
+		ID:     "wait",
+		Name:   "Wait",
+		Config: starriver.TaskConfigure{Params: []starriver.Param{{Name: "WaitingTime", Type: starriver.ParamTypeVariable, Variable: "loop_item"}}},
+	}
+}
+
+func checkTask() starriver.Task {
+	return starriver.Task{
+		ID:     "check",
+		Name:   "TestNode",
+		Config: starriver.TaskConfigure{Params: []starriver.Param{{Name: "Pass", Type: starriver.ParamTypeVariable, Variable: "loop_item"}}},
+	}
+}
+
+func TestMapComponent_Execute(t *testing.T) {
+	registerTestNode()
+	registerTemplate()
+	registerWait()
+	mc := newMapComponent()
+	dc := core.NewDataContext(context.Background(), &mockPipeline{}, nil)
+
+	param := mc.ParameterNew().(*mapParam)
+	param.Items = []interface{}{"60ms", "1ms", "30ms", "10ms"}
+	param.PipelineConf = mapSubConf(waitTask())
+	param.Parallel = 4
+	start := time.Now()
+	resp := mc.Execute(dc, param)
+	assert.True(t, resp.IsPass())
+	// the items run concurrently
+	assert.Less(t, time.Since(start), 100*time.Millisecond)
+
+	results := resp.GetData()["Results"].([]map[string]interface{})
+	if assert.Len(t, results, 4) {
+		for i, item := range param.Items {
+			assert.Equal(t, item, results[i]["out"])
+		}
+	}
+	assert.Equal(t, []string{"", "", "", ""}, resp.GetData()["Errors"])
+}
+
+func TestMapComponent_DefaultParallel(t *testing.T) {
+	registerTemplate()
+	registerWait()
+	mc := newMapComponent()
+	dc := core.NewDataContext(context.Background(), &mockPipeline{}, nil)
+
+	param := mc.ParameterNew().(*mapParam)
+	param.Items = make([]interface{}, mapDefaultParallel)
+	for i := range param.Items {
+		param.Items[i] = "50ms"
+	}
+	param.PipelineConf = mapSubConf(waitTask())
+	param.Parallel = 0
+	start := time.Now()
+	resp := mc.Execute(dc, param)
+	assert.True(t, resp.IsPass())
+	// all the items run at the same time as the default parallel
+	assert.Less(t, time.Since(start), 200*time.Millisecond)
+	assert.Len(t, resp.GetData()["Results"], mapDefaultParallel)
+}
+
+func TestMapComponent_ErrorMode(t *testing.T) {
+	registerTestNode()
+	registerTemplate()
+	mc := newMapComponent()
+	dc := core.NewDataContext(context.Background(), &mockPipeline{}, nil)
+
+	param := mc.ParameterNew().(*mapParam)
+	param.Items = []interface{}{true, false, true}
+	param.PipelineConf = mapSubConf(checkTask())
+	param.Parallel = 1
+	resp := mc.Execute(dc, param)
+	assert.False(t, resp.IsPass())
+	assert.ErrorContains(t, resp.GetError(), "index 1")
+
+	param.ErrorMode = mapCollectErrors
+	resp = mc.Execute(dc, param)
+	assert.True(t, resp.IsPass())
+	results := resp.GetData()["Results"].([]map[string]interface{})
+	assert.Equal(t, "true", results[0]["out"])
+	assert.Nil(t, results[1])
+	assert.Equal(t, "true", results[2]["out"])
+	errs := resp.GetData()["Errors"].([]string)
+	assert.Empty(t, errs[0])
+	assert.Contains(t, errs[1], "index 1")
+	assert.Empty(t, errs[2])
+
+	param.ErrorMode = "ignore"
+	assert.False(t, mc.Execute(dc, param).IsPass())
+}
+
+func TestMapComponent_Cancel(t *testing.T) {
+	registerTemplate()
+	registerWait()
+	mc := newMapComponent()
+	ctx, cancel := context.WithCancel(context.Background())
+	dc := core.NewDataContext(ctx, &mockPipeline{}, nil)
+	time.AfterFunc(20*time.Millisecond, cancel)
+
+	param := mc.ParameterNew().(*mapParam)
+	param.Items = []interface{}{"1s", "1s", "1s", "1s"}
+	param.PipelineConf = mapSubConf(waitTask())
+	param.Parallel = 2
+	start := time.Now()
+	resp := mc.Execute(dc, param)
+	assert.False(t, resp.IsPass())
+	assert.ErrorIs(t, resp.GetError(), context.Canceled)
+	assert.Less(t, time.Since(start), 500*time.Millisecond)
+}
@@ -0,0 +1,51 @@
+package repository
+
+import (
+	"github.com/thanksloving/starriver"
+	"github.com/thanksloving/starriver/helper"
+	"github.com/thanksloving/starriver/registry"
+)
+
+type (
+	waitForSignal struct {
+		helper.SkeletonWithParameter
+	}
+
+	waitForSignalParam struct {
+		Signal string
+	}
+)
+
+var _ starriver.Executable = (*waitForSignal)(nil)
+
+func registerWaitForSignal() {
+	registry.Register("WaitForSignal", "等待外部信号（如人工审批），信号到达前流程处于 blocked 状态，通过 RiverEngine.Signal 发送信号后恢复执行（引擎需要开启运行日志）",
+		func(id string) starriver.Executable {
+			return &waitForSignal{helper.NewSkeletonWithParameter(id, &waitForSignalParam{})}
+		},
+		registry.Input([]starriver.InputParam{
+			{
+				Key:      "Signal",
+				Required: true,
+				Desc:     "信号名，信号携带的数据会保存在工作台的 signal.<信号名> 中",
+			},
+		}),
+		registry.Output(map[string]starriver.OutputValue{
+			"Payload": {
+				Desc: "信号携带的数据",
+			},
+		}),
+	)
+}
+
+func (w *waitForSignal) Execute(dataContext starriver.DataContext, param interface{}) starriver.Response {
+	p := param.(*waitForSignalParam)
+	payload, ok := dataContext.Get(starriver.SignalKey(p.Signal))
+	if !ok {
+		dataContext.Infof("[%q]wait for signal %q", w.ID(), p.Signal)
+		return helper.NewBlockedResponse()
+	}
+	return helper.NewSuccessDataResponse(map[string]interface{}{
+		"Payload": payload,
+	})
+}
@@ -56,25 +56,32 @@ func (s *subPipelineComponent) ParameterNew() interface{} {
 
 func (s *subPipelineComponent) Execute(dataContext starriver.DataContext, param interface{}) starriver.Response {
 	p := param.(*subPipelineParam)
-	
+
 	// Create sub-pipeline
 	subPipeline, err := core.BuildPipeline(p.PipelineConf, starriver.PipelineStatusInit, make(map[string]starriver.TaskStatus))
 	if err != nil {
 		return helper.NewErrorResponse(fmt.Errorf("build sub pipeline error: %v", err))
 	}
-	
+
 	// Create context for sub-pipeline
 	// We inherit the request ID and timeout if possible, but use a new data store
-	ctx := context.WithValue(dataContext.Context(), "X-B3-Traceid", dataContext.GetRequestID())
+	ctx, span := starriver.StartSpan(dataContext.Context(), "sub pipeline "+subPipeline.GetName(),
+		starriver.Attr(starriver.AttrTaskID, s.ID()),
+		starriver.Attr(starriver.AttrPipelineName, subPipeline.GetName()),
+	)
+	defer span.End()
+	ctx = context.WithValue(ctx, "X-B3-Traceid", dataContext.GetRequestID())
 	subDataContext := core.NewDataContext(ctx, subPipeline, p.InputData)
-	
+
 	// Run sub-pipeline
 	result := subPipeline.Run(subDataContext)
-	
+	span.SetAttributes(starriver.Attr(starriver.AttrPipelineStatus, string(result.Status)))
+	span.RecordError(result.Error)
+
 	if result.Status != starriver.PipelineStatusSuccess {
 		return helper.NewErrorResponse(fmt.Errorf("sub pipeline executed failed with status: %s, err: %v", result.Status, result.Error))
 	}
-	
+
 	return helper.NewSuccessDataResponse(map[string]interface{}{
 		"Result": result.Data,
 	})
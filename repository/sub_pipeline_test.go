@@ -21,7 +21,7 @@ func TestSubPipelineComponent_Execute(t *testing.T) {
 	dc := core.NewDataContext(context.Background(), &mockPipeline{}, nil)
 
 	subConf := starriver.PipelineConf{
-		Name: "test_inner",
+		Name:   "test_inner",
 		Result: []string{"test_val"},
 		Pipeline: []starriver.Task{
 			{
@@ -30,8 +30,8 @@ func TestSubPipelineComponent_Execute(t *testing.T) {
 				Config: starriver.TaskConfigure{
 					Params: []starriver.Param{
 						{
-							Name: "Pass",
-							Type: starriver.ParamTypeLiteral,
+							Name:    "Pass",
+							Type:    starriver.ParamTypeLiteral,
 							Literal: true,
 						},
 					},
@@ -43,18 +43,18 @@ func TestSubPipelineComponent_Execute(t *testing.T) {
 				Config: starriver.TaskConfigure{
 					Params: []starriver.Param{
 						{
-							Name: "Template",
-							Type: starriver.ParamTypeLiteral,
+							Name:    "Template",
+							Type:    starriver.ParamTypeLiteral,
 							Literal: "hello world",
 						},
 						{
-							Name: "OutputKey",
-							Type: starriver.ParamTypeLiteral,
+							Name:    "OutputKey",
+							Type:    starriver.ParamTypeLiteral,
 							Literal: "test_val",
 						},
 						{
-							Name: "Shared",
-							Type: starriver.ParamTypeLiteral,
+							Name:    "Shared",
+							Type:    starriver.ParamTypeLiteral,
 							Literal: true,
 						},
 					},
@@ -73,7 +73,7 @@ func TestSubPipelineComponent_Execute(t *testing.T) {
 	resp := sp.Execute(dc, param)
 	assert.True(t, resp.IsPass())
 	assert.NotNil(t, resp.GetData()["Result"])
-	
+
 	resultMap := resp.GetData()["Result"].(map[string]interface{})
 	assert.Equal(t, "hello world", resultMap["test_val"])
 }
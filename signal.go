@@ -0,0 +1,9 @@
+package starriver
+
+// SignalKeyPrefix is the prefix of the key which the payload of a signal is stored with in the shared data store
+const SignalKeyPrefix = "signal."
+
+// SignalKey returns the key of the signal payload in the shared data store, e.g. signal.approval
+func SignalKey(signalName string) string {
+	return SignalKeyPrefix + signalName
+}
@@ -0,0 +1,38 @@
+package starriver
+
+import (
+	"context"
+	"time"
+)
+
+type (
+	// NoopTaskEventHandler ignores all the events, embed it to handle some of the events only
+	NoopTaskEventHandler struct{}
+
+	taskEventHandlerKey struct{}
+)
+
+var _ TaskEventHandler = NoopTaskEventHandler{}
+
+func (NoopTaskEventHandler) OnTaskReady(DataContext, string) {}
+
+func (NoopTaskEventHandler) OnTaskStart(DataContext, string) {}
+
+func (NoopTaskEventHandler) OnTaskEnd(DataContext, string, Response, time.Duration) {}
+
+func (NoopTaskEventHandler) OnTaskSkipped(DataContext, string, SkipReason) {}
+
+func (NoopTaskEventHandler) OnTaskBlocked(DataContext, string, Response) {}
+
+// ContextWithTaskEventHandler returns a copy of ctx carrying the handler, the tasks run with the ctx fire the events to it
+func ContextWithTaskEventHandler(ctx context.Context, handler TaskEventHandler) context.Context {
+	return context.WithValue(ctx, taskEventHandlerKey{}, handler)
+}
+
+// TaskEventHandlerFromContext returns the handler of ctx, it's a no-op handler if ctx has no handler
+func TaskEventHandlerFromContext(ctx context.Context) TaskEventHandler {
+	if handler, ok := ctx.Value(taskEventHandlerKey{}).(TaskEventHandler); ok && handler != nil {
+		return handler
+	}
+	return NoopTaskEventHandler{}
+}
@@ -0,0 +1,182 @@
+package starriver
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+const (
+	AttrPipelineName   = "pipeline.name"
+	AttrRequestID      = "pipeline.request_id"
+	AttrPipelineStatus = "pipeline.status"
+	AttrTaskID         = "task.id"
+	AttrComponent      = "task.component"
+	AttrNamespace      = "task.namespace"
+	AttrTaskStatus     = "task.status"
+	AttrFailureLevel   = "task.failure_level"
+	AttrAttempts       = "task.attempts"
+	AttrParamError     = "task.param_error"
+	AttrLoopIndex      = "loop.index"
+)
+
+type (
+	// Tracer creates the spans of the runs, it can be an adapter of OpenTelemetry or any other tracing system
+	Tracer interface {
+		// Start starts a span as the child of the span in ctx, the returned ctx should be used by the children
+		Start(ctx context.Context, name string, attributes ...Attribute) (context.Context, Span)
+	}
+
+	Span interface {
+		SetAttributes(attributes ...Attribute)
+		RecordError(err error)
+		End()
+	}
+
+	Attribute struct {
+		Key   string
+		Value interface{}
+	}
+
+	// RecordingTracer keeps all the spans in memory, it's useful for test
+	RecordingTracer struct {
+		lock  sync.Mutex
+		spans []*RecordedSpan
+	}
+
+	RecordedSpan struct {
+		tracer     *RecordingTracer
+		ID         int
+		ParentID   int // 0 means root span
+		Name       string
+		Attributes map[string]interface{}
+		Errors     []error
+		StartTime  time.Time
+		EndTime    time.Time
+	}
+
+	noopTracer struct{}
+	noopSpan   struct{}
+
+	tracerKey struct{}
+	spanKey   struct{}
+)
+
+var (
+	_ Tracer = (*noopTracer)(nil)
+	_ Tracer = (*RecordingTracer)(nil)
+	_ Span   = (*noopSpan)(nil)
+	_ Span   = (*RecordedSpan)(nil)
+)
+
+// Attr returns an attribute of span
+func Attr(key string, value interface{}) Attribute {
+	return Attribute{Key: key, Value: value}
+}
+
+// ContextWithTracer returns a copy of ctx carrying the tracer, the spans started from it are reported to the tracer
+func ContextWithTracer(ctx context.Context, tracer Tracer) context.Context {
+	return context.WithValue(ctx, tracerKey{}, tracer)
+}
+
+// TracerFromContext returns the tracer of ctx, it's a no-op tracer if ctx has no tracer
+func TracerFromContext(ctx context.Context) Tracer {
+	if tracer, ok := ctx.Value(tracerKey{}).(Tracer); ok && tracer != nil {
+		return tracer
+	}
+	return noopTracer{}
+}
+
+// SpanFromContext returns the current span of ctx, it's a no-op span if ctx has no span
+func SpanFromContext(ctx context.Context) Span {
+	if span, ok := ctx.Value(spanKey{}).(Span); ok {
+		return span
+	}
+	return noopSpan{}
+}
+
+// StartSpan starts a span with the tracer of ctx, and makes it the current span of the returned ctx
+func StartSpan(ctx context.Context, name string, attributes ...Attribute) (context.Context, Span) {
+	tracer := TracerFromContext(ctx)
+	if _, ok := tracer.(noopTracer); ok {
+		return ctx, noopSpan{}
+	}
+	ctx, span := tracer.Start(ctx, name, attributes...)
+	return context.WithValue(ctx, spanKey{}, span), span
+}
+
+func (noopTracer) Start(ctx context.Context, _ string, _ ...Attribute) (context.Context, Span) {
+	return ctx, noopSpan{}
+}
+
+func (noopSpan) SetAttributes(...Attribute) {}
+
+func (noopSpan) RecordError(error) {}
+
+func (noopSpan) End() {}
+
+func NewRecordingTracer() *RecordingTracer {
+	return &RecordingTracer{}
+}
+
+func (rt *RecordingTracer) Start(ctx context.Context, name string, attributes ...Attribute) (context.Context, Span) {
+	rt.lock.Lock()
+	defer rt.lock.Unlock()
+	span := &RecordedSpan{
+		tracer:     rt,
+		ID:         len(rt.spans) + 1,
+		Name:       name,
+		Attributes: make(map[string]interface{}, len(attributes)),
+		StartTime:  time.Now(),
+	}
+	if parent, ok := SpanFromContext(ctx).(*RecordedSpan); ok && parent.tracer == rt {
+		span.ParentID = parent.ID
+	}
+	for _, attr := range attributes {
+		span.Attributes[attr.Key] = attr.Value
+	}
+	rt.spans = append(rt.spans, span)
+	return ctx, span
+}
+
+// Spans returns the copies of all the spans in the order they started
+func (rt *RecordingTracer) Spans() []RecordedSpan {
+	rt.lock.Lock()
+	defer rt.lock.Unlock()
+	spans := make([]RecordedSpan, len(rt.spans))
+	for idx, span := range rt.spans {
+		spans[idx] = *span
+		spans[idx].tracer = nil
+		spans[idx].Attributes = make(map[string]interface{}, len(span.Attributes))
+		for k, v := range span.Attributes {
+			spans[idx].Attributes[k] = v
+		}
+		spans[idx].Errors = append([]error(nil), span.Errors...)
+	}
+	return spans
+}
+
+func (rs *RecordedSpan) SetAttributes(attributes ...Attribute) {
+	rs.tracer.lock.Lock()
+	defer rs.tracer.lock.Unlock()
+	for _, attr := range attributes {
+		rs.Attributes[attr.Key] = attr.Value
+	}
+}
+
+func (rs *RecordedSpan) RecordError(err error) {
+	if err == nil {
+		return
+	}
+	rs.tracer.lock.Lock()
+	defer rs.tracer.lock.Unlock()
+	rs.Errors = append(rs.Errors, err)
+}
+
+func (rs *RecordedSpan) End() {
+	rs.tracer.lock.Lock()
+	defer rs.tracer.lock.Unlock()
+	if rs.EndTime.IsZero() {
+		rs.EndTime = time.Now()
+	}
+}
@@ -12,6 +12,7 @@ const (
 	PipelineStatusBlocked PipelineStatus = "blocked" // 需要人工介入
 	PipelineStatusFailure PipelineStatus = "failure"
 	PipelineStatusSuccess PipelineStatus = "success"
+	PipelineStatusRunning PipelineStatus = "running" // only reported by the run handle of an asynchronous run
 
 	TaskStatusInit    TaskStatus = "init"
 	TaskStatusBlocked TaskStatus = "blocked"
@@ -31,6 +32,29 @@ const (
 	ConditionEQ ConditionOperator = "=="
 	ConditionNE ConditionOperator = "!="
 	ConditionIn ConditionOperator = "in"
+
+	BackoffFixed       BackoffType = "fixed"
+	BackoffExponential BackoffType = "exponential"
+
+	SkipReasonConditionNotMatch SkipReason = "condition_not_match" // the condition of an upstream edge is not matched
+	SkipReasonUpstreamFailed    SkipReason = "upstream_failed"     // the upstream tasks failed
+	SkipReasonSkipExecution     SkipReason = "skip_execution"      // the task is configured with skip_execution
+	SkipReasonBranchNotTaken    SkipReason = "branch_not_taken"    // the @switch node selected another branch
+	SkipReasonUpstreamSkipped   SkipReason = "upstream_skipped"    // the upstream tasks were skipped, so the skip propagates
+
+	SwitchDefaultBranch = "default" // the branch taken when the value of the @switch node matches no other branch
+
+	HookKeyStatus = "pipeline.status" // the status of the pipeline, the on_success, on_failure and finally tasks can get it
+	HookKeyState  = "pipeline.state"  // the statuses of the tasks, for the on_success, on_failure and finally tasks
+	HookKeyError  = "pipeline.error"  // the error message of the failed pipeline, for the on_failure and finally tasks
+
+	TriggerRuleAllSuccess              TriggerRule = "all_success"                 // all the dependencies succeeded, the default rule
+	TriggerRuleAllDone                 TriggerRule = "all_done"                    // all the dependencies are done, whatever they succeeded or not
+	TriggerRuleOneSuccess              TriggerRule = "one_success"                 // at least one dependency succeeded
+	TriggerRuleOneFailed               TriggerRule = "one_failed"                  // at least one dependency failed
+	TriggerRuleNoneFailed              TriggerRule = "none_failed"                 // no dependency failed, they succeeded or were skipped
+	TriggerRuleNoneFailedMinOneSuccess TriggerRule = "none_failed_min_one_success" // no dependency failed and at least one succeeded
+	TriggerRuleAtLeast                 TriggerRule = "at_least"                    // at least n dependencies succeeded, written as at_least(n)
 )
 
 const (
@@ -46,6 +70,9 @@ type (
 
 	ConditionOperator string
 
+	// TriggerRule decides whether a task runs by the results of its dependencies
+	TriggerRule string
+
 	GraphObject interface {
 		ID() string
 	}
@@ -58,6 +85,19 @@ type (
 		SkipExecution bool           `json:"skip_execution" yaml:"skip_execution"` // skip the executor
 		AbortIfError  bool           `json:"abort_if_error" yaml:"abort_if_error"` // abort the pipeline when error
 		Params        Params         `json:"params" yaml:"params"`                 // custom params
+		Retry         *RetryPolicy   `json:"retry" yaml:"retry"`                   // retry the executor when it fails
+		Cache         *CachePolicy   `json:"cache" yaml:"cache"`                   // reuse the outputs of the same parameters
+	}
+
+	BackoffType string
+
+	RetryPolicy struct {
+		MaxAttempts int            `json:"max_attempts" yaml:"max_attempts"` // total attempts including the first one
+		Backoff     BackoffType    `json:"backoff" yaml:"backoff"`           // fixed or exponential, default is fixed
+		Interval    *time.Duration `json:"interval" yaml:"interval"`         // the wait before the second attempt
+		MaxInterval *time.Duration `json:"max_interval" yaml:"max_interval"` // the upper bound of the exponential wait, default is 1h
+		Jitter      float64        `json:"jitter" yaml:"jitter"`             // random factor in [0, 1] applied to every wait
+		RetryOn     []FailureLevel `json:"retry_on" yaml:"retry_on"`         // retryable failure levels, default is FailureLevelError
 	}
 
 	Params []Param
@@ -120,6 +160,11 @@ type (
 		ParameterNew() interface{}
 	}
 
+	// Simulator returns the fake outputs of the component for the plan, so the downstream tasks can be evaluated
+	Simulator interface {
+		Simulate(dataContext DataContext, param interface{}) map[string]interface{}
+	}
+
 	AfterExecute interface {
 		After(dataContext DataContext, resp Response)
 	}
@@ -140,6 +185,20 @@ type (
 		OnBlocked(dataContext DataContext)
 	}
 
+	// TaskEventHandler receives the lifecycle events of every task, including the tasks of the sub pipelines
+	TaskEventHandler interface {
+		// OnTaskReady the dependencies of the task are done
+		OnTaskReady(dataContext DataContext, taskID string)
+		// OnTaskStart the task starts to execute
+		OnTaskStart(dataContext DataContext, taskID string)
+		// OnTaskEnd the task is executed, whatever the status is
+		OnTaskEnd(dataContext DataContext, taskID string, resp Response, duration time.Duration)
+		// OnTaskSkipped the task is not executed
+		OnTaskSkipped(dataContext DataContext, taskID string, reason SkipReason)
+		// OnTaskBlocked the task is executed and blocked, after OnTaskEnd
+		OnTaskBlocked(dataContext DataContext, taskID string, resp Response)
+	}
+
 	FailureLevel int64
 
 	Result struct {
@@ -147,7 +206,62 @@ type (
 		Snapshot []byte
 		Status   PipelineStatus
 		State    map[string]TaskStatus
+		Skipped  []string       // the tasks not taken by routing or skip_execution, the pipeline may succeed with them
+		Attempts map[string]int // how many times each executed task has been attempted
 		Error    error
+		Report   *Report // the timeline of the run
+
+		Compensations []CompensationRecord // the compensations run because the pipeline failed, in the run order
+		Hooks         []TaskRecord         // the on_success, on_failure and finally tasks, in the run order
+	}
+
+	// CompensationRecord is the result of the compensation of a task
+	CompensationRecord struct {
+		TaskID    string     `json:"task_id"`
+		Component string     `json:"component"`
+		Status    TaskStatus `json:"status"`
+		Error     string     `json:"error,omitempty"`
+		StartedAt time.Time  `json:"started_at"`
+		EndedAt   time.Time  `json:"ended_at"`
+	}
+
+	SkipReason string
+
+	// Report is the timeline of a run, it's JSON-serializable
+	Report struct {
+		StartedAt time.Time    `json:"started_at"`
+		EndedAt   time.Time    `json:"ended_at"`
+		Tasks     []TaskRecord `json:"tasks"` // ordered by the ready time
+	}
+
+	// TaskRecord is what happened to a task in a run, the times are zero if the task was not walked, e.g. the run was cancelled
+	TaskRecord struct {
+		TaskID          string           `json:"task_id"`
+		Status          TaskStatus       `json:"status"`
+		FailureLevel    FailureLevel     `json:"failure_level"`
+		Error           string           `json:"error,omitempty"`
+		Attempts        int              `json:"attempts,omitempty"`
+		CacheHit        bool             `json:"cache_hit,omitempty"` // the outputs are from the result cache, the task was not executed
+		SkipReason      SkipReason       `json:"skip_reason,omitempty"`
+		FailedCondition *FailedCondition `json:"failed_condition,omitempty"`
+		ReadyAt         time.Time        `json:"ready_at"`   // when the dependencies were done
+		StartedAt       time.Time        `json:"started_at"` // zero if the task was not executed
+		EndedAt         time.Time        `json:"ended_at"`
+	}
+
+	// Plan is the result of a dry run, the components are not executed, it's JSON-serializable
+	Plan struct {
+		Run     []string            `json:"run"`               // the tasks which would run, sorted by id
+		Skip    []string            `json:"skip"`              // the tasks which would be skipped, sorted by id
+		Fail    []string            `json:"fail,omitempty"`    // the tasks whose parameters can not be prepared, sorted by id
+		Missing map[string][]string `json:"missing,omitempty"` // the missing required variables of the tasks
+		Tasks   []TaskRecord        `json:"tasks"`             // why the tasks would run or be skipped, ordered by the ready time
+	}
+
+	// FailedCondition is the edge condition which was not matched
+	FailedCondition struct {
+		Depend    string `json:"depend"`    // the upstream task id of the edge
+		Condition string `json:"condition"` // such as "score > 60" or the expression
 	}
 
 	Response interface {